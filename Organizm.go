@@ -15,4 +15,29 @@ type Organism interface {
 	HasBred() bool
 	Breed()
 	GetDiet() []string
+	GetEatingCooldown() int
+	GetTraits() Traits
+	GetActionPoints() int
+	SpendActionPoints(points int) bool
+	UseEnergy(amount int)
+	Die()
+	GetState() BehaviorState
+	SetState(state BehaviorState)
+	GetMemory() *Memory
+	GetParents() []int
+	GetBirthTurn() int
+	SetLineage(parents []int, birthTurn int)
+	Clone() Organism
+}
+
+func NewOrganism(organismType string, id, x, y int) Organism {
+	switch organismType {
+	case "Fox":
+		return NewFox(id, x, y)
+	case "Rabbit":
+		return NewRabbit(id, x, y)
+	case "Grass":
+		return NewGrass(id, x, y)
+	}
+	return nil
 }
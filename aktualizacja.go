@@ -0,0 +1,367 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+type UpdateMode int
+
+const (
+	SequentialUpdate UpdateMode = iota
+	FixedOrderUpdate
+	SynchronousUpdate
+)
+
+var UpdateModeNames = []string{"Sekwencyjna", "Stała kolejność", "Synchroniczna"}
+
+func (m UpdateMode) String() string {
+	if int(m) < len(UpdateModeNames) {
+		return UpdateModeNames[m]
+	}
+	return "?"
+}
+
+type ConflictRule int
+
+const (
+	RandomWins ConflictRule = iota
+	LowestIDWins
+	HighestEnergyWins
+)
+
+var ConflictRuleNames = []string{"Losowo", "Najniższe ID", "Najwięcej energii"}
+
+func (r ConflictRule) String() string {
+	if int(r) < len(ConflictRuleNames) {
+		return ConflictRuleNames[r]
+	}
+	return "?"
+}
+
+type ActionKind int
+
+const (
+	StayAction ActionKind = iota
+	EatAction
+	MoveAction
+	BreedAction
+)
+
+var ActionKeys = []string{"stay", "eat", "move", "breed"}
+
+func (k ActionKind) String() string {
+	if int(k) < len(ActionKeys) {
+		return ActionKeys[k]
+	}
+	return "?"
+}
+
+type Intent struct {
+	Actor  Organism
+	Kind   ActionKind
+	Target Organism
+	X, Y   int
+}
+
+type claim struct {
+	cell bool
+	a, b int
+}
+
+func (i Intent) claims() []claim {
+	claims := []claim{{a: i.Actor.GetID()}}
+	if i.Target != nil {
+		claims = append(claims, claim{a: i.Target.GetID()})
+	}
+	if i.Kind == MoveAction || i.Kind == BreedAction {
+		claims = append(claims, claim{cell: true, a: i.X, b: i.Y})
+	}
+	return claims
+}
+
+func (w *World) simulateSequential() {
+	organisms := w.getAllLivingOrganisms()
+	if w.UpdateMode == FixedOrderUpdate {
+		sortByID(organisms)
+	} else {
+		w.rng.Shuffle(len(organisms), func(i, j int) {
+			organisms[i], organisms[j] = organisms[j], organisms[i]
+		})
+	}
+
+	for _, organism := range organisms {
+		if organism.GetEnergy() <= 0 {
+			continue
+		}
+		w.act(organism)
+	}
+}
+
+func (w *World) act(organism Organism) {
+	moves := 0
+	for organism.GetEnergy() > 0 && organism.GetActionPoints() > 0 {
+		intent := w.decide(organism, moves, w.rng)
+		if intent.Kind == StayAction || !w.apply(intent, moves) {
+			break
+		}
+		if intent.Kind == MoveAction {
+			moves++
+		}
+		if intent.Kind == BreedAction {
+			break
+		}
+	}
+}
+
+func (w *World) simulateSynchronous() {
+	organisms := w.getAllLivingOrganisms()
+	sortByID(organisms)
+	moves := make(map[int]int, len(organisms))
+	done := make(map[int]bool, len(organisms))
+
+	for round := 0; ; round++ {
+		var intents []Intent
+		for _, intent := range w.declareIntents(organisms, moves, done, round) {
+			if intent.Kind == StayAction {
+				done[intent.Actor.GetID()] = true
+				continue
+			}
+			intents = append(intents, intent)
+		}
+		if len(intents) == 0 {
+			return
+		}
+
+		start := w.metrics.start()
+		granted, rejected := w.resolveConflicts(intents)
+		w.metrics.stop(ConflictPhase, start)
+		for _, intent := range granted {
+			id := intent.Actor.GetID()
+			if !w.apply(intent, moves[id]) {
+				done[id] = true
+				continue
+			}
+			switch intent.Kind {
+			case MoveAction:
+				moves[id]++
+			case BreedAction:
+				done[id] = true
+			}
+		}
+		for _, intent := range rejected {
+			intent.Actor.SpendActionPoints(1)
+			w.metrics.count(RejectedIntents)
+		}
+	}
+}
+
+func (w *World) declareIntents(organisms []Organism, moves map[int]int, done map[int]bool, round int) []Intent {
+	intents := make([]Intent, len(organisms))
+	declare := func(i int) {
+		organism := organisms[i]
+		id := organism.GetID()
+		if done[id] || organism.GetEnergy() <= 0 {
+			intents[i] = Intent{Actor: organism, Kind: StayAction}
+			return
+		}
+		rng := newSplitMix(w.Seed, int64(w.Turn), int64(round), int64(id))
+		intents[i] = w.decide(organism, moves[id], rng)
+	}
+
+	if w.Workers <= 1 {
+		for i := range organisms {
+			declare(i)
+		}
+		return intents
+	}
+
+	jobs := make(chan []int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < w.Workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tile := range jobs {
+				for _, i := range tile {
+					declare(i)
+				}
+			}
+		}()
+	}
+	for _, tile := range w.partitionTiles(organisms) {
+		jobs <- tile
+	}
+	close(jobs)
+	wg.Wait()
+	return intents
+}
+
+func (w *World) partitionTiles(organisms []Organism) [][]int {
+	tileSize := w.TileSize
+	if tileSize <= 0 {
+		tileSize = 32
+	}
+	tilesPerRow := (w.Width + tileSize - 1) / tileSize
+	index := make(map[int]int)
+	var tiles [][]int
+	for i, organism := range organisms {
+		x, y := organism.GetPosition()
+		key := (y/tileSize)*tilesPerRow + x/tileSize
+		t, ok := index[key]
+		if !ok {
+			t = len(tiles)
+			index[key] = t
+			tiles = append(tiles, nil)
+		}
+		tiles[t] = append(tiles[t], i)
+	}
+	return tiles
+}
+
+func (w *World) parallelFor(n int, fn func(i int)) {
+	if w.Workers <= 1 || n < w.Workers {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	batch := (n + w.Workers - 1) / w.Workers
+	for start := 0; start < n; start += batch {
+		end := min(start+batch, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *World) resolveConflicts(intents []Intent) ([]Intent, []Intent) {
+	ordered := append([]Intent(nil), intents...)
+	switch w.ConflictRule {
+	case LowestIDWins:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Actor.GetID() < ordered[j].Actor.GetID()
+		})
+	case HighestEnergyWins:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Actor.GetEnergy() > ordered[j].Actor.GetEnergy()
+		})
+	default:
+		w.rng.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	}
+
+	var granted, rejected []Intent
+	claimed := make(map[claim]bool)
+	for _, intent := range ordered {
+		claims := intent.claims()
+		free := true
+		for _, c := range claims {
+			if claimed[c] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			rejected = append(rejected, intent)
+			continue
+		}
+		for _, c := range claims {
+			claimed[c] = true
+		}
+		granted = append(granted, intent)
+	}
+	return granted, rejected
+}
+
+func (w *World) decide(organism Organism, moves int, rng randSource) Intent {
+	intent := Intent{Actor: organism, Kind: StayAction}
+	if organism.GetEnergy() <= 0 || organism.GetActionPoints() <= 0 {
+		return intent
+	}
+	w.metrics.count(Decisions)
+	start := w.metrics.start()
+	w.observe(organism)
+	organism.SetState(w.chooseState(organism))
+	w.metrics.stop(PerceptionPhase, start)
+
+	start = w.metrics.start()
+	food := w.planEating(organism)
+	w.metrics.stop(FeedingPhase, start)
+	if food != nil {
+		intent.Kind = EatAction
+		intent.Target = food
+		intent.X, intent.Y = food.GetPosition()
+		return intent
+	}
+	if moves < organism.GetTraits().Speed && organism.CanMove() {
+		start = w.metrics.start()
+		pos, ok := w.planMove(organism, organism.GetState(), rng)
+		w.metrics.stop(MovementPhase, start)
+		if ok {
+			intent.Kind = MoveAction
+			intent.X, intent.Y = pos[0], pos[1]
+			return intent
+		}
+	}
+	start = w.metrics.start()
+	partner, pos, ok := w.planBreeding(organism, rng)
+	w.metrics.stop(BreedingPhase, start)
+	if ok {
+		intent.Kind = BreedAction
+		intent.Target = partner
+		intent.X, intent.Y = pos[0], pos[1]
+	}
+	return intent
+}
+
+func (w *World) apply(intent Intent, moves int) bool {
+	organism := intent.Actor
+	traits := organism.GetTraits()
+	switch intent.Kind {
+	case EatAction:
+		start := w.metrics.start()
+		defer w.metrics.stop(FeedingPhase, start)
+		if !w.eat(organism, intent.Target) {
+			return false
+		}
+		organism.SpendActionPoints(traits.EatCost)
+	case MoveAction:
+		start := w.metrics.start()
+		defer w.metrics.stop(MovementPhase, start)
+		x, y := organism.GetPosition()
+		if !w.MoveOrganism(x, y, intent.X, intent.Y) {
+			return false
+		}
+		organism.SpendActionPoints(1)
+		if moves > 0 {
+			organism.UseEnergy(traits.SprintCost)
+		}
+	case BreedAction:
+		start := w.metrics.start()
+		defer w.metrics.stop(BreedingPhase, start)
+		if !w.breed(organism, intent.Target, intent.X, intent.Y) {
+			return false
+		}
+		organism.SpendActionPoints(traits.BreedCost)
+	default:
+		return false
+	}
+	if w.Trajectories != nil {
+		w.actions[organism.GetID()] = append(w.actions[organism.GetID()], intent.Kind)
+	}
+	return true
+}
+
+func sortByID(organisms []Organism) {
+	sort.Slice(organisms, func(i, j int) bool {
+		return organisms[i].GetID() < organisms[j].GetID()
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+	"gonum.org/v1/gonum/stat"
+)
+
+const minAnalysisTurns = 16
+
+type SeriesAnalysis struct {
+	Mean       float64
+	CV         float64
+	PeriodFFT  float64
+	PeriodACF  float64
+	Period     float64
+	Amplitude  float64
+	Oscillates bool
+}
+
+type OscillationReport struct {
+	FromTurn, ToTurn int
+	Fox, Rabbit      SeriesAnalysis
+	PhaseLag         float64
+	PhaseDegrees     float64
+}
+
+func AnalyzeOscillations(records []TurnRecord, burnIn int) (OscillationReport, error) {
+	if burnIn > 0 {
+		records = records[min(burnIn, len(records)):]
+	}
+	report := OscillationReport{}
+	if len(records) < minAnalysisTurns {
+		return report, fmt.Errorf("za krótka seria: %d tur, potrzeba co najmniej %d", len(records), minAnalysisTurns)
+	}
+	report.FromTurn, report.ToTurn = records[0].Turn, records[len(records)-1].Turn
+
+	foxes := make([]float64, len(records))
+	rabbits := make([]float64, len(records))
+	for i, record := range records {
+		foxes[i] = float64(record.Counts["Fox"])
+		rabbits[i] = float64(record.Counts["Rabbit"])
+	}
+	report.Fox = analyzeSeries(foxes)
+	report.Rabbit = analyzeSeries(rabbits)
+
+	period := report.Rabbit.Period
+	if !report.Rabbit.Oscillates {
+		period = report.Fox.Period
+	}
+	if report.Fox.Oscillates && report.Rabbit.Oscillates && period > 0 {
+		report.PhaseLag = float64(bestLag(rabbits, foxes, int(math.Ceil(period))))
+		report.PhaseDegrees = 360 * report.PhaseLag / period
+	}
+	return report, nil
+}
+
+func analyzeSeries(series []float64) SeriesAnalysis {
+	mean, std := stat.MeanStdDev(series, nil)
+	analysis := SeriesAnalysis{Mean: mean}
+	if mean > 0 {
+		analysis.CV = std / mean
+	}
+	if std == 0 {
+		return analysis
+	}
+
+	centered := make([]float64, len(series))
+	for i, value := range series {
+		centered[i] = value - mean
+	}
+	fft := fourier.NewFFT(len(centered))
+	coefficients := fft.Coefficients(nil, centered)
+	best := 0
+	for k := 1; k < len(coefficients); k++ {
+		if best == 0 || cmplx.Abs(coefficients[k]) > cmplx.Abs(coefficients[best]) {
+			best = k
+		}
+	}
+	if best > 0 {
+		analysis.PeriodFFT = float64(len(centered)) / float64(best)
+		analysis.Amplitude = 2 * cmplx.Abs(coefficients[best]) / float64(len(centered))
+	}
+
+	analysis.PeriodACF = autocorrelationPeriod(centered)
+	analysis.Period = analysis.PeriodACF
+	if analysis.Period == 0 {
+		analysis.Period = analysis.PeriodFFT
+	}
+	analysis.Oscillates = analysis.PeriodACF > 0
+	return analysis
+}
+
+func autocorrelation(centered []float64, lag int) float64 {
+	var sum, norm float64
+	for i, value := range centered {
+		norm += value * value
+		if i+lag < len(centered) {
+			sum += value * centered[i+lag]
+		}
+	}
+	if norm == 0 {
+		return 0
+	}
+	return sum / norm
+}
+
+func autocorrelationPeriod(centered []float64) float64 {
+	maxLag := len(centered) / 2
+	crossed := false
+	for lag := 1; lag < maxLag; lag++ {
+		value := autocorrelation(centered, lag)
+		if value < 0 {
+			crossed = true
+			continue
+		}
+		if crossed && value > 0.1 &&
+			value >= autocorrelation(centered, lag-1) && value >= autocorrelation(centered, lag+1) {
+			return float64(lag)
+		}
+	}
+	return 0
+}
+
+func bestLag(leader, follower []float64, maxLag int) int {
+	best, bestValue := 0, math.Inf(-1)
+	for lag := 0; lag <= maxLag && lag < len(leader)-1; lag++ {
+		value := stat.Correlation(leader[:len(leader)-lag], follower[lag:], nil)
+		if value > bestValue {
+			best, bestValue = lag, value
+		}
+	}
+	return best
+}
+
+func (a SeriesAnalysis) String() string {
+	if !a.Oscillates {
+		return fmt.Sprintf("średnio %.1f, CV %.2f, brak wyraźnego cyklu (FFT: %.1f tur)", a.Mean, a.CV, a.PeriodFFT)
+	}
+	return fmt.Sprintf("średnio %.1f, CV %.2f, okres %.1f tur (FFT %.1f), amplituda %.1f",
+		a.Mean, a.CV, a.Period, a.PeriodFFT, a.Amplitude)
+}
+
+func (r OscillationReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Analiza cykli, tury %d–%d\n", r.FromTurn, r.ToTurn)
+	fmt.Fprintf(&b, "Lisy: %s\n", r.Fox)
+	fmt.Fprintf(&b, "Króliki: %s\n", r.Rabbit)
+	if r.Fox.Oscillates && r.Rabbit.Oscillates {
+		fmt.Fprintf(&b, "Opóźnienie lisów za królikami: %.0f tur (%.0f°)\n", r.PhaseLag, r.PhaseDegrees)
+	}
+	return b.String()
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+)
+
+const shadeSteps = 10
+
+type AnimationOptions struct {
+	Renderer
+	FrameSkip int
+	Delay     int
+}
+
+func DefaultAnimationOptions() AnimationOptions {
+	options := AnimationOptions{Renderer: DefaultRenderer(), FrameSkip: 1, Delay: 10}
+	options.MaxSize = maxAnimationSize
+	return options
+}
+
+type Animation struct {
+	Options AnimationOptions
+	frames  []*image.Paletted
+	turns   []int
+	palette color.Palette
+}
+
+func NewAnimation(options AnimationOptions) *Animation {
+	if options.FrameSkip < 1 {
+		options.FrameSkip = 1
+	}
+	a := &Animation{Options: options}
+	for _, name := range colorNames {
+		a.palette = append(a.palette, options.color(name))
+	}
+	if options.EnergyShading {
+		for _, organismType := range placementOrder {
+			for i := 0; i < shadeSteps; i++ {
+				strength := 0.3 + 0.7*float64(i)/float64(shadeSteps-1)
+				a.palette = append(a.palette, shade(options.color(organismType), options.color("Open"), strength))
+			}
+		}
+	}
+	if len(options.Sprites) > 0 {
+		a.palette = append(a.palette, palette.WebSafe...)
+	}
+	return a
+}
+
+func (a *Animation) Capture(w *World) {
+	if a == nil {
+		return
+	}
+	for len(a.turns) > 0 && a.turns[len(a.turns)-1] >= w.Turn {
+		a.turns = a.turns[:len(a.turns)-1]
+		a.frames = a.frames[:len(a.frames)-1]
+	}
+	if w.Turn%a.Options.FrameSkip != 0 {
+		return
+	}
+	a.frames = append(a.frames, a.render(w))
+	a.turns = append(a.turns, w.Turn)
+}
+
+func (a *Animation) Len() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.frames)
+}
+
+func (a *Animation) render(w *World) *image.Paletted {
+	frame := image.NewPaletted(a.Options.bounds(w), a.palette)
+	if len(a.Options.Sprites) == 0 {
+		a.Options.Draw(frame, w)
+		return frame
+	}
+	full := image.NewRGBA(frame.Bounds())
+	a.Options.Draw(full, w)
+	draw.FloydSteinberg.Draw(frame, frame.Bounds(), full, image.Point{})
+	return frame
+}
+
+func (a *Animation) WriteGIF(out io.Writer) error {
+	if len(a.frames) == 0 {
+		return fmt.Errorf("brak klatek do zapisania")
+	}
+	animation := &gif.GIF{Image: a.frames, Delay: make([]int, len(a.frames))}
+	for i := range animation.Delay {
+		animation.Delay[i] = a.Options.Delay
+	}
+	return gif.EncodeAll(out, animation)
+}
+
+func (a *Animation) WriteAPNG(out io.Writer) error {
+	if len(a.frames) == 0 {
+		return fmt.Errorf("brak klatek do zapisania")
+	}
+	if _, err := out.Write([]byte("\x89PNG\r\n\x1a\n")); err != nil {
+		return err
+	}
+	sequence := uint32(0)
+	for i, frame := range a.frames {
+		var buffer bytes.Buffer
+		if err := png.Encode(&buffer, frame); err != nil {
+			return err
+		}
+		chunks, err := pngChunks(buffer.Bytes())
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			for _, chunk := range chunks {
+				if chunk.kind == "IHDR" || chunk.kind == "PLTE" || chunk.kind == "tRNS" {
+					if err := writePNGChunk(out, chunk.kind, chunk.data); err != nil {
+						return err
+					}
+				}
+				if chunk.kind == "IHDR" {
+					control := binary.BigEndian.AppendUint32(nil, uint32(len(a.frames)))
+					control = binary.BigEndian.AppendUint32(control, 0)
+					if err := writePNGChunk(out, "acTL", control); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		bounds := frame.Bounds()
+		control := binary.BigEndian.AppendUint32(nil, sequence)
+		control = binary.BigEndian.AppendUint32(control, uint32(bounds.Dx()))
+		control = binary.BigEndian.AppendUint32(control, uint32(bounds.Dy()))
+		control = binary.BigEndian.AppendUint32(control, 0)
+		control = binary.BigEndian.AppendUint32(control, 0)
+		control = binary.BigEndian.AppendUint16(control, uint16(a.Options.Delay))
+		control = binary.BigEndian.AppendUint16(control, 100)
+		control = append(control, 0, 0)
+		if err := writePNGChunk(out, "fcTL", control); err != nil {
+			return err
+		}
+		sequence++
+
+		for _, chunk := range chunks {
+			if chunk.kind != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				err = writePNGChunk(out, "IDAT", chunk.data)
+			} else {
+				err = writePNGChunk(out, "fdAT", append(binary.BigEndian.AppendUint32(nil, sequence), chunk.data...))
+				sequence++
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return writePNGChunk(out, "IEND", nil)
+}
+
+func (a *Animation) Export(out io.Writer, format string) error {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "gif":
+		return a.WriteGIF(out)
+	case "apng", "png":
+		return a.WriteAPNG(out)
+	}
+	return fmt.Errorf("nieznany format animacji %q", format)
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func pngChunks(data []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	data = data[8:]
+	for len(data) >= 12 {
+		length := int(binary.BigEndian.Uint32(data))
+		if len(data) < 12+length {
+			return nil, fmt.Errorf("uszkodzony fragment PNG")
+		}
+		chunks = append(chunks, pngChunk{kind: string(data[4:8]), data: data[8 : 8+length]})
+		data = data[12+length:]
+	}
+	return chunks, nil
+}
+
+func writePNGChunk(out io.Writer, kind string, data []byte) error {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	_, err := out.Write(chunk)
+	return err
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RunRecord struct {
+	ID           int            `json:"id"`
+	Started      time.Time      `json:"started"`
+	Duration     time.Duration  `json:"duration"`
+	Width        int            `json:"width"`
+	Height       int            `json:"height"`
+	Foxes        int            `json:"foxes"`
+	Rabbits      int            `json:"rabbits"`
+	Grass        int            `json:"grass"`
+	Obstacles    int            `json:"obstacles"`
+	Hedgerows    int            `json:"hedgerows"`
+	MaxTurns     int            `json:"max_turns"`
+	Seed         int64          `json:"seed"`
+	Map          string         `json:"map,omitempty"`
+	Scenario     string         `json:"scenario,omitempty"`
+	UpdateMode   UpdateMode     `json:"mode"`
+	ConflictRule ConflictRule   `json:"conflicts"`
+	Workers      int            `json:"workers"`
+	Placement    string         `json:"placement"`
+	Stop         string         `json:"stop"`
+	Reason       string         `json:"reason"`
+	Turns        int            `json:"turns"`
+	Final        map[string]int `json:"final"`
+	History      []TurnRecord   `json:"history"`
+}
+
+func (r RunRecord) String() string {
+	return fmt.Sprintf("#%d\t%s\t%dx%d\tF%d R%d G%d\tziarno %d\ttury %d\tF%d R%d G%d\t%s",
+		r.ID, r.Started.Format("2006-01-02 15:04"), r.Width, r.Height, r.Foxes, r.Rabbits, r.Grass,
+		r.Seed, r.Turns, r.Final["Fox"], r.Final["Rabbit"], r.Final["Grass"], r.Reason)
+}
+
+const archiveLockTimeout = 30 * time.Second
+
+type Archive struct {
+	path string
+	runs []RunRecord
+}
+
+func OpenArchive(path string) (*Archive, error) {
+	archive := &Archive{path: path}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return archive, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(data))) > 0 {
+			var run RunRecord
+			if err := json.Unmarshal(data, &run); err != nil {
+				return nil, fmt.Errorf("%s, linia %d: %v", path, line, err)
+			}
+			archive.runs = append(archive.runs, run)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return archive, nil
+}
+
+func (a *Archive) Save(run RunRecord) (int, error) {
+	unlock, err := lockArchive(a.path)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	last, err := lastRunID(a.path)
+	if err != nil {
+		return 0, err
+	}
+	run.ID = last + 1
+	data, err := json.Marshal(run)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	a.runs = append(a.runs, run)
+	return run.ID, nil
+}
+
+func lockArchive(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(archiveLockTimeout)
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("archiwum %s jest zablokowane (usuń %s, jeśli nic go nie zapisuje)", path, lock)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func lastRunID(path string) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	for chunk := int64(4096); ; chunk *= 2 {
+		start := max(0, size-chunk)
+		data := make([]byte, size-start)
+		if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		data = bytes.TrimRight(data, " \t\r\n")
+		newline := bytes.LastIndexByte(data, '\n')
+		if newline < 0 && start > 0 {
+			continue
+		}
+		if len(data) == 0 {
+			return 0, nil
+		}
+		var last struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(data[newline+1:], &last); err != nil {
+			return 0, fmt.Errorf("%s, ostatnia linia: %v", path, err)
+		}
+		return last.ID, nil
+	}
+}
+
+func (a *Archive) List(filter RunFilter) []RunRecord {
+	var runs []RunRecord
+	for _, run := range a.runs {
+		if filter.Match(run) {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+func (a *Archive) Get(id int) (RunRecord, bool) {
+	for _, run := range a.runs {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return RunRecord{}, false
+}
+
+type runCondition struct {
+	field    string
+	operator string
+	value    string
+}
+
+type RunFilter []runCondition
+
+var runFilterOperators = []string{">=", "<=", "!=", "=", ">", "<", "~"}
+
+func ParseRunFilter(spec string) (RunFilter, error) {
+	var filter RunFilter
+	spec = strings.ReplaceAll(spec, " and ", ",")
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		condition, ok := runCondition{}, false
+		for _, operator := range runFilterOperators {
+			if field, value, found := strings.Cut(part, operator); found {
+				condition = runCondition{strings.TrimSpace(field), operator, strings.TrimSpace(value)}
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("warunek %q: brak operatora", part)
+		}
+		if _, known := runField(RunRecord{}, condition.field); !known {
+			return nil, fmt.Errorf("warunek %q: nieznane pole %q", part, condition.field)
+		}
+		filter = append(filter, condition)
+	}
+	return filter, nil
+}
+
+func (f RunFilter) Match(run RunRecord) bool {
+	for _, condition := range f {
+		if !condition.match(run) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c runCondition) match(run RunRecord) bool {
+	actual, _ := runField(run, c.field)
+	if c.operator == "~" {
+		return strings.Contains(strings.ToLower(actual), strings.ToLower(c.value))
+	}
+	order := strings.Compare(actual, c.value)
+	left, leftErr := strconv.ParseFloat(actual, 64)
+	right, rightErr := strconv.ParseFloat(c.value, 64)
+	if leftErr == nil && rightErr == nil {
+		order = cmp.Compare(left, right)
+	}
+	switch c.operator {
+	case "=":
+		return order == 0
+	case "!=":
+		return order != 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	}
+	return false
+}
+
+func runField(run RunRecord, field string) (string, bool) {
+	switch field {
+	case "id":
+		return strconv.Itoa(run.ID), true
+	case "seed":
+		return strconv.FormatInt(run.Seed, 10), true
+	case "width":
+		return strconv.Itoa(run.Width), true
+	case "height":
+		return strconv.Itoa(run.Height), true
+	case "grid":
+		return fmt.Sprintf("%dx%d", run.Width, run.Height), true
+	case "foxes":
+		return strconv.Itoa(run.Foxes), true
+	case "rabbits":
+		return strconv.Itoa(run.Rabbits), true
+	case "grass":
+		return strconv.Itoa(run.Grass), true
+	case "obstacles":
+		return strconv.Itoa(run.Obstacles), true
+	case "hedgerows":
+		return strconv.Itoa(run.Hedgerows), true
+	case "workers":
+		return strconv.Itoa(run.Workers), true
+	case "mode":
+		return strconv.Itoa(int(run.UpdateMode)), true
+	case "conflicts":
+		return strconv.Itoa(int(run.ConflictRule)), true
+	case "placement":
+		return run.Placement, true
+	case "map":
+		return run.Map, true
+	case "scenario":
+		return run.Scenario, true
+	case "reason":
+		return run.Reason, true
+	case "turns":
+		return strconv.Itoa(run.Turns), true
+	case "final_fox":
+		return strconv.Itoa(run.Final["Fox"]), true
+	case "final_rabbit":
+		return strconv.Itoa(run.Final["Rabbit"]), true
+	case "final_grass":
+		return strconv.Itoa(run.Final["Grass"]), true
+	case "started":
+		return run.Started.Format(time.RFC3339), true
+	}
+	return "", false
+}
@@ -0,0 +1,142 @@
+package main
+
+type Traits struct {
+	Speed           int
+	ActionPoints    int
+	SprintCost      int
+	EatCost         int
+	BreedCost       int
+	BreedEnergy     int
+	VisionRadius    int
+	CoverDetection  int
+	HungerThreshold int
+	MateThreshold   int
+	FleeDistance    int
+	MemoryCapacity  int
+	MemoryDecay     float64
+}
+
+var SpeciesTraits = map[string]Traits{
+	"Fox": {
+		Speed:           2,
+		ActionPoints:    4,
+		SprintCost:      1,
+		EatCost:         1,
+		BreedCost:       1,
+		BreedEnergy:     4,
+		VisionRadius:    5,
+		CoverDetection:  1,
+		HungerThreshold: 8,
+		MateThreshold:   6,
+		FleeDistance:    0,
+		MemoryCapacity:  8,
+		MemoryDecay:     0.85,
+	},
+	"Rabbit": {
+		Speed:           1,
+		ActionPoints:    3,
+		SprintCost:      1,
+		EatCost:         1,
+		BreedCost:       1,
+		BreedEnergy:     3,
+		VisionRadius:    4,
+		CoverDetection:  1,
+		HungerThreshold: 5,
+		MateThreshold:   4,
+		FleeDistance:    3,
+		MemoryCapacity:  6,
+		MemoryDecay:     0.8,
+	},
+	"Grass": {
+		Speed:           0,
+		ActionPoints:    1,
+		SprintCost:      0,
+		EatCost:         0,
+		BreedCost:       1,
+		BreedEnergy:     4,
+		VisionRadius:    0,
+		CoverDetection:  0,
+		HungerThreshold: 0,
+		MateThreshold:   0,
+		FleeDistance:    0,
+		MemoryCapacity:  0,
+		MemoryDecay:     0,
+	},
+}
+
+type actor struct {
+	traits       Traits
+	actionPoints int
+	state        BehaviorState
+	memory       *Memory
+	parents      []int
+	birthTurn    int
+}
+
+func newActor(organismType string) actor {
+	traits := SpeciesTraits[organismType]
+	a := actor{
+		traits:       traits,
+		actionPoints: traits.ActionPoints,
+	}
+	if traits.MemoryCapacity > 0 {
+		a.memory = NewMemory(traits.MemoryCapacity, traits.MemoryDecay)
+	}
+	return a
+}
+
+func (a *actor) GetTraits() Traits {
+	return a.traits
+}
+
+func (a *actor) GetActionPoints() int {
+	return a.actionPoints
+}
+
+func (a *actor) SpendActionPoints(points int) bool {
+	if points > a.actionPoints {
+		return false
+	}
+	a.actionPoints -= points
+	return true
+}
+
+func (a *actor) GetState() BehaviorState {
+	return a.state
+}
+
+func (a *actor) SetState(state BehaviorState) {
+	a.state = state
+}
+
+func (a *actor) GetMemory() *Memory {
+	return a.memory
+}
+
+func (a *actor) GetParents() []int {
+	return a.parents
+}
+
+func (a *actor) GetBirthTurn() int {
+	return a.birthTurn
+}
+
+func (a *actor) SetLineage(parents []int, birthTurn int) {
+	a.parents = append([]int(nil), parents...)
+	a.birthTurn = birthTurn
+}
+
+func (a *actor) clone() actor {
+	clone := *a
+	if a.memory != nil {
+		memory := *a.memory
+		memory.entries = append([]MemoryEntry(nil), a.memory.entries...)
+		clone.memory = &memory
+	}
+	return clone
+}
+
+func (a *actor) startTurn() {
+	a.actionPoints = a.traits.ActionPoints
+	a.memory.Decay()
+}
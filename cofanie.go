@@ -0,0 +1,118 @@
+package main
+
+import "fmt"
+
+type Branch []*World
+
+func (b Branch) Span() (int, int) {
+	return b[0].Turn, b[len(b)-1].Turn
+}
+
+func (b Branch) String() string {
+	from, to := b.Span()
+	return fmt.Sprintf("gałąź: tury %d–%d", from, to)
+}
+
+type Timeline struct {
+	Capacity     int
+	Budget       int
+	KeepBranches bool
+	MaxBranches  int
+	Branches     []Branch
+	snapshots    []*World
+}
+
+func NewTimeline(capacity int) *Timeline {
+	return &Timeline{Capacity: capacity, MaxBranches: 4}
+}
+
+func (t *Timeline) Record(w *World) {
+	t.truncate(w.Turn, t.KeepBranches)
+	t.snapshots = append(t.snapshots, w.Clone())
+	t.trim()
+}
+
+func (t *Timeline) truncate(turn int, keep bool) {
+	for i, snapshot := range t.snapshots {
+		if snapshot.Turn >= turn {
+			if keep {
+				t.Branches = append(t.Branches, append(Branch(nil), t.snapshots[i:]...))
+				if t.MaxBranches > 0 && len(t.Branches) > t.MaxBranches {
+					t.Branches = t.Branches[len(t.Branches)-t.MaxBranches:]
+				}
+			}
+			t.snapshots = t.snapshots[:i]
+			return
+		}
+	}
+}
+
+func (t *Timeline) trim() {
+	if t.Capacity > 0 && len(t.snapshots) > t.Capacity {
+		t.snapshots = t.snapshots[len(t.snapshots)-t.Capacity:]
+	}
+	if t.Budget <= 0 {
+		return
+	}
+	total := 0
+	for _, snapshot := range t.snapshots {
+		total += snapshotSize(snapshot)
+	}
+	for _, branch := range t.Branches {
+		for _, snapshot := range branch {
+			total += snapshotSize(snapshot)
+		}
+	}
+	for total > t.Budget && len(t.Branches) > 0 {
+		for _, snapshot := range t.Branches[0] {
+			total -= snapshotSize(snapshot)
+		}
+		t.Branches = t.Branches[1:]
+	}
+	for total > t.Budget && len(t.snapshots) > 1 {
+		total -= snapshotSize(t.snapshots[0])
+		t.snapshots = t.snapshots[1:]
+	}
+}
+
+func snapshotSize(w *World) int {
+	return len(w.index.All()) + w.terrain.Len()
+}
+
+func (t *Timeline) Len() int {
+	return len(t.snapshots)
+}
+
+func (t *Timeline) Span() (int, int) {
+	if len(t.snapshots) == 0 {
+		return 0, 0
+	}
+	return t.snapshots[0].Turn, t.snapshots[len(t.snapshots)-1].Turn
+}
+
+func (t *Timeline) Restore(turn int) (*World, bool) {
+	for _, snapshot := range t.snapshots {
+		if snapshot.Turn == turn {
+			return snapshot.Clone(), true
+		}
+	}
+	return nil, false
+}
+
+func (t *Timeline) SwitchBranch(index int) (Branch, bool) {
+	if index < 0 || index >= len(t.Branches) {
+		return nil, false
+	}
+	branch := t.Branches[index]
+	t.Branches = append(t.Branches[:index:index], t.Branches[index+1:]...)
+	from, _ := branch.Span()
+	t.truncate(from, true)
+	t.snapshots = append(t.snapshots, branch...)
+	t.trim()
+	return branch, true
+}
+
+func (t *Timeline) Clear() {
+	t.snapshots = nil
+	t.Branches = nil
+}
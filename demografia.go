@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type DeathCause int
+
+const (
+	EatenDeath DeathCause = iota
+	StarvationDeath
+	RemovedDeath
+)
+
+var DeathCauseNames = []string{"zjedzone", "wyczerpanie", "usunięte"}
+
+func (c DeathCause) String() string {
+	if int(c) < len(DeathCauseNames) {
+		return DeathCauseNames[c]
+	}
+	return "?"
+}
+
+type deathKey struct {
+	organismType string
+	cause        DeathCause
+}
+
+type mealKey struct {
+	eater, food string
+}
+
+const ageBucket = 5
+
+type EnergyStats struct {
+	Mean, Median float64
+	Min, Max     int
+}
+
+type SpeciesStats struct {
+	Count    int
+	Births   int
+	Arrivals int
+	Deaths   int
+	Causes   map[DeathCause]int
+	Energy   EnergyStats
+	MeanAge  float64
+	Ages     []int
+	Density  float64
+	Eaten    int
+	Predated int
+}
+
+type Demographics struct {
+	Turn            int
+	Species         map[string]SpeciesStats
+	PredationEvents int
+	GrazingEvents   int
+	GrassCover      float64
+	HabitableCells  int
+}
+
+func (s SpeciesStats) AgeDistribution() string {
+	parts := make([]string, 0, len(s.Ages))
+	for i, count := range s.Ages {
+		if count > 0 {
+			parts = append(parts, fmt.Sprintf("%d-%d: %d", i*ageBucket, (i+1)*ageBucket-1, count))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (w *World) Demographics() Demographics {
+	habitable := w.Width * w.Height
+	w.terrain.Each(func(x, y int, terrain Terrain) {
+		if terrain.BlocksMovement() {
+			habitable--
+		}
+	})
+	d := Demographics{
+		Turn:           w.Turn,
+		Species:        make(map[string]SpeciesStats, len(placementOrder)),
+		HabitableCells: habitable,
+	}
+	for _, organismType := range placementOrder {
+		organisms := w.index.ByType(organismType)
+		stats := SpeciesStats{
+			Count:    len(organisms),
+			Births:   w.births[organismType],
+			Arrivals: w.arrivals[organismType],
+			Deaths:   w.deaths[organismType],
+			Causes:   make(map[DeathCause]int),
+		}
+		for cause := range DeathCauseNames {
+			if count := w.causes[deathKey{organismType, DeathCause(cause)}]; count > 0 {
+				stats.Causes[DeathCause(cause)] = count
+			}
+		}
+		for key, count := range w.meals {
+			if key.eater == organismType {
+				stats.Eaten += count
+			}
+			if key.food == organismType {
+				stats.Predated += count
+			}
+		}
+		if len(organisms) > 0 {
+			energies := make([]int, len(organisms))
+			totalEnergy, totalAge := 0, 0
+			for i, organism := range organisms {
+				energies[i] = organism.GetEnergy()
+				totalEnergy += energies[i]
+				age := max(0, w.Turn-organism.GetBirthTurn())
+				totalAge += age
+				for len(stats.Ages) <= age/ageBucket {
+					stats.Ages = append(stats.Ages, 0)
+				}
+				stats.Ages[age/ageBucket]++
+			}
+			sort.Ints(energies)
+			middle := len(energies) / 2
+			median := float64(energies[middle])
+			if len(energies)%2 == 0 {
+				median = float64(energies[middle-1]+energies[middle]) / 2
+			}
+			stats.Energy = EnergyStats{
+				Mean:   float64(totalEnergy) / float64(len(organisms)),
+				Median: median,
+				Min:    energies[0],
+				Max:    energies[len(energies)-1],
+			}
+			stats.MeanAge = float64(totalAge) / float64(len(organisms))
+		}
+		if habitable > 0 {
+			stats.Density = float64(stats.Count) / float64(habitable)
+		}
+		d.Species[organismType] = stats
+	}
+	d.PredationEvents = w.meals[mealKey{"Fox", "Rabbit"}]
+	d.GrazingEvents = w.meals[mealKey{"Rabbit", "Grass"}]
+	d.GrassCover = d.Species["Grass"].Density * 100
+	return d
+}
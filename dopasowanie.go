@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/optimize"
+	"gonum.org/v1/gonum/stat"
+)
+
+const lvSubsteps = 4
+
+type LotkaVolterra struct {
+	Alpha, Beta, Gamma, Delta float64
+	Rabbit0, Fox0             float64
+}
+
+func (p LotkaVolterra) derivative(rabbits, foxes float64) (float64, float64) {
+	return p.Alpha*rabbits - p.Beta*rabbits*foxes, p.Delta*rabbits*foxes - p.Gamma*foxes
+}
+
+func (p LotkaVolterra) Solve(turns int) (rabbits, foxes []float64) {
+	rabbits = make([]float64, turns)
+	foxes = make([]float64, turns)
+	x, y := p.Rabbit0, p.Fox0
+	h := 1.0 / lvSubsteps
+	for t := 0; t < turns; t++ {
+		rabbits[t], foxes[t] = x, y
+		for s := 0; s < lvSubsteps; s++ {
+			k1x, k1y := p.derivative(x, y)
+			k2x, k2y := p.derivative(x+h/2*k1x, y+h/2*k1y)
+			k3x, k3y := p.derivative(x+h/2*k2x, y+h/2*k2y)
+			k4x, k4y := p.derivative(x+h*k3x, y+h*k3y)
+			x += h / 6 * (k1x + 2*k2x + 2*k3x + k4x)
+			y += h / 6 * (k1y + 2*k2y + 2*k3y + k4y)
+		}
+	}
+	return rabbits, foxes
+}
+
+type LotkaVolterraFit struct {
+	Params          LotkaVolterra
+	FromTurn        int
+	Rabbits, Foxes  []float64
+	R2Rabbit, R2Fox float64
+	RMSERabbit      float64
+	RMSEFox         float64
+	FuncEvaluations int
+	observedRabbits []float64
+	observedFoxes   []float64
+}
+
+func FitLotkaVolterra(records []TurnRecord, burnIn int) (LotkaVolterraFit, error) {
+	if burnIn > 0 {
+		records = records[min(burnIn, len(records)):]
+	}
+	fit := LotkaVolterraFit{}
+	if len(records) < minAnalysisTurns {
+		return fit, fmt.Errorf("za krótka seria: %d tur, potrzeba co najmniej %d", len(records), minAnalysisTurns)
+	}
+	fit.FromTurn = records[0].Turn
+	fit.observedRabbits = make([]float64, len(records))
+	fit.observedFoxes = make([]float64, len(records))
+	for i, record := range records {
+		fit.observedRabbits[i] = float64(record.Counts["Rabbit"])
+		fit.observedFoxes[i] = float64(record.Counts["Fox"])
+	}
+	rabbitMean, foxMean := stat.Mean(fit.observedRabbits, nil), stat.Mean(fit.observedFoxes, nil)
+	if rabbitMean == 0 || foxMean == 0 {
+		return fit, fmt.Errorf("jeden z gatunków nie występuje w analizowanym okresie")
+	}
+
+	decode := func(x []float64) LotkaVolterra {
+		return LotkaVolterra{
+			Alpha:   math.Exp(x[0]),
+			Beta:    math.Exp(x[1]),
+			Gamma:   math.Exp(x[2]),
+			Delta:   math.Exp(x[3]),
+			Rabbit0: math.Exp(x[4]),
+			Fox0:    math.Exp(x[5]),
+		}
+	}
+	objective := func(x []float64) float64 {
+		rabbits, foxes := decode(x).Solve(len(records))
+		sum := 0.0
+		for i := range rabbits {
+			dr := (rabbits[i] - fit.observedRabbits[i]) / rabbitMean
+			df := (foxes[i] - fit.observedFoxes[i]) / foxMean
+			sum += dr*dr + df*df
+		}
+		if math.IsNaN(sum) || math.IsInf(sum, 0) {
+			return math.MaxFloat64
+		}
+		return sum
+	}
+
+	periods := []float64{20, 40, 80}
+	if analysis := analyzeSeries(fit.observedRabbits); analysis.Period > 0 {
+		periods = append(periods, analysis.Period, analysis.PeriodFFT)
+	}
+	problem := optimize.Problem{Func: objective}
+	var start []float64
+	best := math.Inf(1)
+	for _, period := range periods {
+		for _, ratio := range []float64{0.5, 1, 2} {
+			omega := 2 * math.Pi / period
+			alpha, gamma := omega*math.Sqrt(ratio), omega/math.Sqrt(ratio)
+			candidate := []float64{
+				math.Log(alpha),
+				math.Log(alpha / foxMean),
+				math.Log(gamma),
+				math.Log(gamma / rabbitMean),
+				math.Log(max(fit.observedRabbits[0], 0.5)),
+				math.Log(max(fit.observedFoxes[0], 0.5)),
+			}
+			result, _ := optimize.Minimize(problem, candidate, &optimize.Settings{FuncEvaluations: 2000}, &optimize.NelderMead{})
+			if result == nil {
+				continue
+			}
+			fit.FuncEvaluations += result.FuncEvaluations
+			if result.F < best {
+				best, start = result.F, result.X
+			}
+		}
+	}
+	if start == nil {
+		return fit, fmt.Errorf("nie udało się dopasować modelu")
+	}
+	for restart := 0; restart < 3; restart++ {
+		result, _ := optimize.Minimize(problem, start, &optimize.Settings{FuncEvaluations: 20000}, &optimize.NelderMead{})
+		if result == nil {
+			break
+		}
+		fit.FuncEvaluations += result.FuncEvaluations
+		start = result.X
+	}
+
+	fit.Params = decode(start)
+	fit.Rabbits, fit.Foxes = fit.Params.Solve(len(records))
+	fit.R2Rabbit, fit.RMSERabbit = goodnessOfFit(fit.observedRabbits, fit.Rabbits)
+	fit.R2Fox, fit.RMSEFox = goodnessOfFit(fit.observedFoxes, fit.Foxes)
+	return fit, nil
+}
+
+func goodnessOfFit(observed, fitted []float64) (r2, rmse float64) {
+	mean := stat.Mean(observed, nil)
+	var residual, total float64
+	for i := range observed {
+		residual += (observed[i] - fitted[i]) * (observed[i] - fitted[i])
+		total += (observed[i] - mean) * (observed[i] - mean)
+	}
+	rmse = math.Sqrt(residual / float64(len(observed)))
+	if total == 0 {
+		return 0, rmse
+	}
+	return 1 - residual/total, rmse
+}
+
+func (f LotkaVolterraFit) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Lotka-Volterra od tury %d (%d ocen funkcji)\n", f.FromTurn, f.FuncEvaluations)
+	fmt.Fprintf(&b, "α=%.4f β=%.5f γ=%.4f δ=%.5f\n", f.Params.Alpha, f.Params.Beta, f.Params.Gamma, f.Params.Delta)
+	fmt.Fprintf(&b, "Punkt równowagi: %.1f królików, %.1f lisów\n", f.Params.Gamma/f.Params.Delta, f.Params.Alpha/f.Params.Beta)
+	fmt.Fprintf(&b, "Króliki: R²=%.3f, RMSE=%.2f\n", f.R2Rabbit, f.RMSERabbit)
+	fmt.Fprintf(&b, "Lisy: R²=%.3f, RMSE=%.2f\n", f.R2Fox, f.RMSEFox)
+	return b.String()
+}
@@ -3,17 +3,17 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"image/color"
 	"image/png"
 	"strconv"
+	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 	"gonum.org/v1/plot/vg/vgimg"
@@ -22,9 +22,15 @@ import (
 type GUI struct {
 	app         fyne.App
 	window      fyne.Window
+	mu          sync.Mutex
 	world       *World
 	simulation  *Simulation
 	gridWidget  *widget.RichText
+	viewXSlider *widget.Slider
+	viewYSlider *widget.Slider
+	viewLabel   *widget.Label
+	viewX       int
+	viewY       int
 	chartWidget *fyne.Container
 	chartImage  *widget.Icon
 	widthEntry  *widget.Entry
@@ -32,17 +38,68 @@ type GUI struct {
 	foxEntry    *widget.Entry
 	rabbitEntry *widget.Entry
 	grassEntry  *widget.Entry
+	rockEntry   *widget.Entry
+	hedgeEntry  *widget.Entry
+	seedEntry   *widget.Entry
+	modeSelect  *widget.Select
+	ruleSelect  *widget.Select
+	workerEntry *widget.Entry
+	placeSelect *widget.Select
+	stopEntry   *widget.Entry
+	stop        StopCondition
 	startButton *widget.Button
 	resetButton *widget.Button
 	stepButton  *widget.Button
+	backButton  *widget.Button
+	scrubSlider *widget.Slider
+	branchCheck *widget.Check
+	branchList  *widget.Select
+	timeline    *Timeline
+	scenario    *Scenario
+	scenarioBtn *widget.Button
+	mapBtn      *widget.Button
+	randomBtn   *widget.Button
+	layout      *World
+	logLabel    *widget.Label
 	turnLabel   *widget.Label
-	statsLabel  *widget.Label
-	turnData    []float64
-	foxData     []float64
-	rabbitData  []float64
-	grassData   []float64
+	statsCells  [][]*widget.Label
+	statsPanel  *fyne.Container
+	statsExtra  *widget.Label
+	analyzeBtn  *widget.Button
+	burnInEntry *widget.Entry
+	analysis    *widget.Label
+	fitBtn      *widget.Button
+	fit         *LotkaVolterraFit
+	inspectX    *widget.Entry
+	inspectY    *widget.Entry
+	inspectBtn  *widget.Button
+	inspectInfo *widget.Label
+	diagCheck   *widget.Check
+	diagLabel   *widget.Label
+	recordCheck *widget.Check
+	pathCheck   *widget.Check
+	trailCheck  *widget.Check
+	trackEntry  *widget.Entry
+	heatSelect  *widget.Select
+	heatWindow  *widget.Entry
+	cellEntry   *widget.Entry
+	skipEntry   *widget.Entry
+	animation   *Animation
+	renderTime  time.Duration
 }
 
+const (
+	viewportWidth  = 40
+	viewportHeight = 30
+	chartWindow    = 50
+	trailLength    = 10
+	timelineBudget = 2000000
+)
+
+var statsRows = []string{"Liczebność", "Narodziny", "Napływ", "Zgony (zj./wycz./us.)", "Energia śr./med.", "Energia min–max", "Wiek śr.", "Gęstość"}
+
+var heatmapNames = []string{"Brak", "Lisy", "Króliki", "Trawa"}
+
 type Simulation struct {
 	world   *World
 	running bool
@@ -65,6 +122,7 @@ func NewGUI() *GUI {
 
 	gui.initializeComponents()
 	gui.setupLayout()
+	gui.setupMenu()
 	gui.resetToDefaults()
 
 	return gui
@@ -81,12 +139,131 @@ func (g *GUI) initializeComponents() {
 	g.rabbitEntry.SetText("15")
 	g.grassEntry = widget.NewEntry()
 	g.grassEntry.SetText("50")
-	g.startButton = widget.NewButton("▶ Start", g.toggleSimulation)
-	g.resetButton = widget.NewButton("🔄 Reset", g.resetSimulation)
-	g.stepButton = widget.NewButton("⏯ Krok", g.stepSimulation)
+	g.rockEntry = widget.NewEntry()
+	g.rockEntry.SetText("10")
+	g.hedgeEntry = widget.NewEntry()
+	g.hedgeEntry.SetText("4")
+	g.seedEntry = widget.NewEntry()
+	g.seedEntry.SetPlaceHolder("losowe")
+	g.modeSelect = widget.NewSelect(UpdateModeNames, func(string) { g.locked(g.applyUpdateSettings)() })
+	g.modeSelect.SetSelectedIndex(int(SequentialUpdate))
+	g.ruleSelect = widget.NewSelect(ConflictRuleNames, func(string) { g.locked(g.applyUpdateSettings)() })
+	g.ruleSelect.SetSelectedIndex(int(RandomWins))
+	g.placeSelect = widget.NewSelect(PlacementNames, nil)
+	g.placeSelect.SetSelectedIndex(int(UniformPlacement))
+	g.stopEntry = widget.NewEntry()
+	g.stopEntry.SetText("allextinct")
+	g.workerEntry = widget.NewEntry()
+	g.workerEntry.SetText("1")
+	g.workerEntry.OnChanged = func(string) { g.locked(g.applyUpdateSettings)() }
+	g.startButton = widget.NewButton("▶ Start", g.locked(g.toggleSimulation))
+	g.resetButton = widget.NewButton("🔄 Reset", g.locked(g.resetSimulation))
+	g.stepButton = widget.NewButton("⏯ Krok", g.locked(g.stepSimulation))
+	g.backButton = widget.NewButton("⏪ Wstecz", g.locked(g.stepBack))
+	g.scrubSlider = widget.NewSlider(0, 1)
+	g.scrubSlider.OnChanged = func(value float64) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.world != nil && int(value) != g.world.Turn {
+			g.rewindTo(int(value))
+		}
+	}
+	g.branchCheck = widget.NewCheck("Zachowaj gałąź", func(keep bool) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.timeline != nil {
+			g.timeline.KeepBranches = keep
+		}
+	})
+	g.branchList = widget.NewSelect(nil, func(choice string) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if index := g.branchList.SelectedIndex(); index >= 0 {
+			g.switchBranch(index)
+		}
+	})
+	g.branchList.PlaceHolder = "Brak zachowanych gałęzi"
+	g.scenarioBtn = widget.NewButton("📜 Scenariusz", g.loadScenario)
+	g.mapBtn = widget.NewButton("🗺 Mapa", g.loadMap)
+	g.randomBtn = widget.NewButton("🎲 Losowy układ", func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.layout = nil
+		g.mapBtn.SetText("🗺 Mapa")
+		g.resetSimulation()
+	})
+	g.logLabel = widget.NewLabel("")
 	g.turnLabel = widget.NewLabel("Tura: 0")
-	g.statsLabel = widget.NewLabel("Populacja:\n🦊 Lisy: 0\n🐰 Króliki: 0\n🌱 Trawa: 0")
+	g.statsPanel = container.NewGridWithColumns(len(placementOrder)+1,
+		widget.NewLabel(""), widget.NewLabel("🦊"), widget.NewLabel("🐰"), widget.NewLabel("🌱"))
+	g.statsCells = make([][]*widget.Label, len(statsRows))
+	for row, name := range statsRows {
+		g.statsPanel.Add(widget.NewLabelWithStyle(name, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for range placementOrder {
+			cell := widget.NewLabel("")
+			g.statsCells[row] = append(g.statsCells[row], cell)
+			g.statsPanel.Add(cell)
+		}
+	}
+	g.statsExtra = widget.NewLabel("")
+	g.analyzeBtn = widget.NewButton("📈 Analiza cykli", g.locked(g.analyzeOscillations))
+	g.burnInEntry = widget.NewEntry()
+	g.burnInEntry.SetText("0")
+	g.analysis = widget.NewLabel("")
+	g.fitBtn = widget.NewButton("🧮 Dopasuj Lotka-Volterra", g.locked(g.fitLotkaVolterra))
+	g.inspectX = widget.NewEntry()
+	g.inspectX.SetPlaceHolder("x")
+	g.inspectY = widget.NewEntry()
+	g.inspectY.SetPlaceHolder("y")
+	g.inspectBtn = widget.NewButton("🔍 Sprawdź", g.locked(g.inspectOrganism))
+	g.inspectInfo = widget.NewLabel("")
+	g.diagCheck = widget.NewCheck("Diagnostyka", func(enabled bool) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.world != nil {
+			g.world.EnableMetrics(enabled)
+		}
+		g.updateDiagnostics()
+	})
+	g.diagLabel = widget.NewLabel("")
+	g.cellEntry = widget.NewEntry()
+	g.cellEntry.SetText("8")
+	g.skipEntry = widget.NewEntry()
+	g.skipEntry.SetText("1")
+	g.pathCheck = widget.NewCheck("🧭 Zapisuj trajektorie", func(bool) { g.locked(g.applyTrajectories)() })
+	g.trailCheck = widget.NewCheck("👣 Pokaż ślady", func(bool) { g.locked(g.updateGrid)() })
+	g.trackEntry = widget.NewEntry()
+	g.trackEntry.SetPlaceHolder("ID, np. 3,7 (puste = wszystkie)")
+	g.trackEntry.OnChanged = func(string) { g.locked(g.applyTracking)() }
+	g.heatSelect = widget.NewSelect(heatmapNames, func(string) { g.locked(g.applyHeatmap)() })
+	g.heatSelect.SetSelectedIndex(0)
+	g.heatWindow = widget.NewEntry()
+	g.heatWindow.SetText("0")
+	g.heatWindow.OnChanged = func(text string) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if window, err := strconv.Atoi(text); err == nil && window >= 0 && g.world != nil && g.world.Occupancy != nil {
+			g.world.Occupancy.Window = window
+		}
+	}
+	g.recordCheck = widget.NewCheck("🎬 Nagrywaj animację", func(bool) { g.locked(g.startRecording)() })
 	g.gridWidget = widget.NewRichText()
+	g.viewXSlider = widget.NewSlider(0, 1)
+	g.viewXSlider.OnChanged = func(value float64) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.viewX = int(value)
+		g.updateGrid()
+	}
+	g.viewYSlider = widget.NewSlider(0, 1)
+	g.viewYSlider.Orientation = widget.Vertical
+	g.viewYSlider.OnChanged = func(value float64) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.viewY = int(g.viewYSlider.Max - value)
+		g.updateGrid()
+	}
+	g.viewLabel = widget.NewLabel("")
 	g.chartImage = widget.NewIcon(nil)
 	g.chartWidget = container.NewVBox(
 		widget.NewLabel("Wykres Populacji"),
@@ -103,23 +280,62 @@ func (g *GUI) setupLayout() {
 			widget.NewFormItem("Lisy:", g.foxEntry),
 			widget.NewFormItem("Króliki:", g.rabbitEntry),
 			widget.NewFormItem("Trawa:", g.grassEntry),
+			widget.NewFormItem("Przeszkody:", g.rockEntry),
+			widget.NewFormItem("Żywopłoty:", g.hedgeEntry),
+			widget.NewFormItem("Rozmieszczenie:", g.placeSelect),
+			widget.NewFormItem("Ziarno:", g.seedEntry),
+			widget.NewFormItem("Aktualizacja:", g.modeSelect),
+			widget.NewFormItem("Konflikty:", g.ruleSelect),
+			widget.NewFormItem("Wątki:", g.workerEntry),
+			widget.NewFormItem("Stop:", g.stopEntry),
 		),
 	)
 	controlsBox := container.NewVBox(
 		widget.NewSeparator(),
 		g.startButton,
 		g.stepButton,
+		g.backButton,
+		g.scrubSlider,
+		g.branchCheck,
+		g.branchList,
 		g.resetButton,
+		g.scenarioBtn,
+		container.NewGridWithColumns(2, g.mapBtn, g.randomBtn),
 		widget.NewSeparator(),
 		g.turnLabel,
-		g.statsLabel,
+		g.statsPanel,
+		g.statsExtra,
+		container.NewBorder(nil, nil, g.analyzeBtn, nil,
+			widget.NewForm(widget.NewFormItem("Pomiń tur:", g.burnInEntry))),
+		g.fitBtn,
+		g.analysis,
+		widget.NewSeparator(),
+		container.NewGridWithColumns(3, g.inspectX, g.inspectY, g.inspectBtn),
+		g.inspectInfo,
+		widget.NewSeparator(),
+		g.diagCheck,
+		g.diagLabel,
+		widget.NewSeparator(),
+		g.pathCheck,
+		container.NewBorder(nil, nil, g.trailCheck, nil, g.trackEntry),
+		widget.NewForm(
+			widget.NewFormItem("Mapa cieplna:", g.heatSelect),
+			widget.NewFormItem("Okno (tury, 0 = wszystkie):", g.heatWindow),
+		),
+		g.recordCheck,
+		widget.NewForm(
+			widget.NewFormItem("Komórka (px):", g.cellEntry),
+			widget.NewFormItem("Co ile tur:", g.skipEntry),
+		),
+		widget.NewSeparator(),
+		g.logLabel,
 	)
 
 	gridScroll := container.NewScroll(g.gridWidget)
 	gridScroll.SetMinSize(fyne.NewSize(500, 400))
 
 	gridCard := container.NewBorder(
-		nil, nil, nil,
+		g.viewLabel, g.viewXSlider, nil, g.viewYSlider,
 		gridScroll,
 	)
 
@@ -155,39 +371,398 @@ func (g *GUI) createWorld() {
 	foxCount, _ := strconv.Atoi(g.foxEntry.Text)
 	rabbitCount, _ := strconv.Atoi(g.rabbitEntry.Text)
 	grassCount, _ := strconv.Atoi(g.grassEntry.Text)
-	if width < 5 || width > 50 {
+	rockCount, _ := strconv.Atoi(g.rockEntry.Text)
+	hedgeCount, _ := strconv.Atoi(g.hedgeEntry.Text)
+	if width < 5 || width > 10000 {
 		width = 20
 	}
-	if height < 5 || height > 50 {
+	if height < 5 || height > 10000 {
 		height = 15
 	}
-	if foxCount < 0 || foxCount > 50 {
+	area := width * height
+	if foxCount < 0 || foxCount > area/4 {
 		foxCount = 5
 	}
-	if rabbitCount < 0 || rabbitCount > 100 {
+	if rabbitCount < 0 || rabbitCount > area/2 {
 		rabbitCount = 15
 	}
-	if grassCount < 0 || grassCount > 200 {
+	if grassCount < 0 || grassCount > area {
 		grassCount = 50
 	}
+	if rockCount < 0 || rockCount > area/4 {
+		rockCount = 10
+	}
+	if hedgeCount < 0 || hedgeCount > area/10 {
+		hedgeCount = 4
+	}
 
-	g.world = NewWorld(width, height)
-	g.world.PopulateRandomly(foxCount, rabbitCount, grassCount)
+	if g.layout != nil {
+		g.world = g.layout.Clone()
+	} else {
+		g.world = NewWorld(width, height)
+	}
+	if seed, err := strconv.ParseInt(g.seedEntry.Text, 10, 64); err == nil {
+		g.world.SetSeed(seed)
+	} else if g.layout != nil {
+		g.world.SetSeed(time.Now().UnixNano())
+	}
+	g.applyUpdateSettings()
+	g.world.EnableMetrics(g.diagCheck.Checked)
+	g.world.History = NewHistory()
+	g.world.Lineage = NewLineage()
+	g.fit = nil
+	g.world.Scenario = g.scenario
+	if g.layout == nil {
+		g.world.GenerateTerrain(rockCount, hedgeCount)
+		g.world.Populate(PlacementStrategy(g.placeSelect.SelectedIndex()), map[string]int{
+			"Fox":    foxCount,
+			"Rabbit": rabbitCount,
+			"Grass":  grassCount,
+		})
+	}
+
+	if stop, err := ParseStopConditions(g.stopEntry.Text); err == nil {
+		g.stop = stop
+	} else {
+		g.stop = AllExtinct{Species: []string{"Fox", "Rabbit"}}
+		g.world.Log(err.Error())
+	}
+	SetClocksRunning(g.stop, false)
 
 	g.simulation = &Simulation{
 		world:   g.world,
 		running: false,
-		stopCh:  make(chan bool),
 	}
-	g.turnData = []float64{}
-	g.foxData = []float64{}
-	g.rabbitData = []float64{}
-	g.grassData = []float64{}
+	g.world.History.Record(g.world)
+	g.world.Lineage.Record(g.world)
+	g.startRecording()
+	g.timeline = NewTimeline(200)
+	g.timeline.Budget = timelineBudget
+	g.timeline.KeepBranches = g.branchCheck.Checked
+	g.timeline.Record(g.world)
+	g.applyTrajectories()
+	g.applyHeatmap()
+	g.updateScrubber()
+	g.resetViewport()
 
 	g.updateDisplay()
 	g.updateChart()
 }
 
+func (g *GUI) applyUpdateSettings() {
+	if g.world == nil {
+		return
+	}
+	g.world.UpdateMode = UpdateMode(g.modeSelect.SelectedIndex())
+	g.world.ConflictRule = ConflictRule(g.ruleSelect.SelectedIndex())
+	if workers, err := strconv.Atoi(g.workerEntry.Text); err == nil && workers >= 1 && workers <= 64 {
+		g.world.Workers = workers
+	}
+}
+
+func (g *GUI) setupMenu() {
+	g.window.SetMainMenu(fyne.NewMainMenu(
+		fyne.NewMenu("Plik",
+			fyne.NewMenuItem("Eksportuj historię (CSV)…", g.locked(func() { g.exportHistory("csv") })),
+			fyne.NewMenuItem("Eksportuj historię (JSON)…", g.locked(func() { g.exportHistory("json") })),
+			fyne.NewMenuItem("Eksportuj rodowód…", g.locked(g.exportLineage)),
+			fyne.NewMenuItem("Eksportuj trajektorie…", g.locked(g.exportTrajectories)),
+			fyne.NewMenuItem("Eksportuj mapę cieplną…", g.locked(g.exportHeatmap)),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Eksportuj wykres…", g.locked(func() { g.exportChart(false) })),
+			fyne.NewMenuItem("Eksportuj wykres całej historii…", g.locked(func() { g.exportChart(true) })),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Zapisz obraz siatki (PNG)…", g.locked(g.exportFrame)),
+			fyne.NewMenuItem("Eksportuj animację (GIF)…", g.locked(func() { g.exportAnimation("gif") })),
+			fyne.NewMenuItem("Eksportuj animację (APNG)…", g.locked(func() { g.exportAnimation("apng") })),
+		),
+	))
+}
+
+func (g *GUI) exportHistory(format string) {
+	if g.world == nil || g.world.History == nil {
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if err := (&History{Records: g.world.History.Until(g.world.Turn)}).Export(writer, format); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName("historia." + format)
+	save.Show()
+}
+
+func (g *GUI) exportLineage() {
+	if g.world == nil || g.world.Lineage == nil {
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if err := g.world.Lineage.Until(g.world.Turn).Export(writer, writer.URI().Extension()); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName("rodowod.dot")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".dot", ".nwk", ".tsv"}))
+	save.Show()
+}
+
+func (g *GUI) applyTracking() {
+	if g.world == nil || g.world.Trajectories == nil {
+		return
+	}
+	ids, err := ParseTrajectoryIDs(g.trackEntry.Text)
+	if err != nil {
+		return
+	}
+	g.world.Trajectories.Selected = make(map[int]bool)
+	for _, id := range ids {
+		g.world.Trajectories.Selected[id] = true
+	}
+	g.updateGrid()
+}
+
+func (g *GUI) applyTrajectories() {
+	if g.world == nil {
+		return
+	}
+	if !g.pathCheck.Checked {
+		g.world.Trajectories = nil
+	} else if g.world.Trajectories == nil {
+		g.world.Trajectories = NewTrajectories()
+		g.applyTracking()
+		g.world.Trajectories.Record(g.world)
+	}
+	g.updateGrid()
+}
+
+func (g *GUI) exportTrajectories() {
+	if g.world == nil || g.world.Trajectories == nil {
+		dialog.ShowInformation("Trajektorie", "Zaznacz „Zapisuj trajektorie” i wykonaj kilka tur.", g.window)
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if err := g.world.Trajectories.Until(g.world.Turn).Export(writer, writer.URI().Extension()); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName("trajektorie.csv")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".geojson"}))
+	save.Show()
+}
+
+func (g *GUI) applyHeatmap() {
+	if g.world == nil {
+		return
+	}
+	if g.heatSelect.SelectedIndex() <= 0 {
+		g.world.Occupancy = nil
+	} else if g.world.Occupancy == nil {
+		window, _ := strconv.Atoi(g.heatWindow.Text)
+		g.world.Occupancy = NewOccupancy(max(0, window))
+		g.world.Occupancy.Horizon = g.timeline.Capacity
+		g.world.Occupancy.Record(g.world)
+	}
+	g.updateGrid()
+}
+
+func (g *GUI) setWorld(world *World) {
+	world.History = g.world.History
+	world.Lineage = g.world.Lineage
+	world.Trajectories = g.world.Trajectories
+	world.Occupancy = g.world.Occupancy
+	g.world = world
+	g.simulation.world = world
+}
+
+func (g *GUI) heatmapSpecies() []string {
+	if index := g.heatSelect.SelectedIndex(); index > 0 {
+		return []string{placementOrder[index-1]}
+	}
+	return nil
+}
+
+func (g *GUI) exportHeatmap() {
+	if g.world == nil || g.world.Occupancy == nil {
+		dialog.ShowInformation("Mapa cieplna", "Wybierz gatunek w polu „Mapa cieplna”, aby zbierać dane o obecności.", g.window)
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if err := g.world.Occupancy.Until(g.world.Turn).Export(writer, writer.URI().Extension(), g.heatmapSpecies()); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName("mapa_cieplna.png")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".csv"}))
+	save.Show()
+}
+
+func (g *GUI) exportChart(fullHistory bool) {
+	if g.world == nil || g.world.History.Len() == 0 {
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		records := g.world.History.Window(chartWindow, g.world.Turn)
+		if fullHistory {
+			records = g.world.History.Until(g.world.Turn)
+		}
+		chart := NewPopulationChart(records)
+		AddFitOverlay(chart, g.fit, records)
+		if err := WriteChart(chart, writer, writer.URI().Extension(), 300); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName("wykres.svg")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".svg", ".pdf", ".eps", ".png"}))
+	save.Show()
+}
+
+func (g *GUI) exportFrame() {
+	if g.world == nil {
+		return
+	}
+	renderer := DefaultRenderer()
+	if size, err := strconv.Atoi(g.cellEntry.Text); err == nil && size >= 1 && size <= 64 {
+		renderer.CellSize = size
+	}
+	renderer.GridLines = renderer.CellSize >= 6
+	renderer.EnergyShading = true
+	frame := renderer.Render(g.world)
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		if err := png.Encode(writer, frame); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName(fmt.Sprintf("tura_%06d.png", g.world.Turn))
+	save.Show()
+}
+
+func (g *GUI) startRecording() {
+	if g.world == nil || !g.recordCheck.Checked {
+		g.animation = nil
+		return
+	}
+	options := DefaultAnimationOptions()
+	if size, err := strconv.Atoi(g.cellEntry.Text); err == nil && size >= 1 && size <= 64 {
+		options.CellSize = size
+	}
+	if skip, err := strconv.Atoi(g.skipEntry.Text); err == nil && skip >= 1 {
+		options.FrameSkip = skip
+	}
+	g.animation = NewAnimation(options)
+	g.animation.Capture(g.world)
+}
+
+func (g *GUI) exportAnimation(format string) {
+	if g.animation.Len() == 0 {
+		dialog.ShowInformation("Animacja", "Zaznacz „Nagrywaj animację” i wykonaj kilka tur.", g.window)
+		return
+	}
+	extension := format
+	if format == "apng" {
+		extension = "png"
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		defer writer.Close()
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if err := g.animation.Export(writer, format); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	save.SetFileName("symulacja." + extension)
+	save.Show()
+}
+
+func (g *GUI) loadScenario() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+		scenario, err := ParseScenario(reader)
+		if err != nil {
+			dialog.ShowError(err, g.window)
+			return
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.scenario = scenario
+		g.scenarioBtn.SetText(fmt.Sprintf("📜 Scenariusz (%d)", len(scenario.Events)))
+		g.resetSimulation()
+	}, g.window)
+}
+
+func (g *GUI) loadMap() {
+	dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+		layout, err := ParseMap(reader)
+		if err != nil {
+			dialog.ShowError(err, g.window)
+			return
+		}
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.layout = layout
+		g.mapBtn.SetText("🗺 " + reader.URI().Name())
+		g.resetSimulation()
+	}, g.window)
+}
+
+func (g *GUI) locked(fn func()) func() {
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		fn()
+	}
+}
+
+func setSliderQuietly(slider *widget.Slider, value float64) {
+	onChanged := slider.OnChanged
+	slider.OnChanged = nil
+	slider.SetValue(value)
+	slider.OnChanged = onChanged
+}
+
 func (g *GUI) toggleSimulation() {
 	if g.simulation == nil {
 		return
@@ -207,21 +782,26 @@ func (g *GUI) startSimulation() {
 
 	g.simulation.running = true
 	g.startButton.SetText("⏸ Pauza")
+	SetClocksRunning(g.stop, true)
 
-	g.simulation.ticker = time.NewTicker(500 * time.Millisecond)
+	simulation := g.simulation
+	simulation.ticker = time.NewTicker(500 * time.Millisecond)
+	simulation.stopCh = make(chan bool)
 
-	go func() {
+	go func(ticker *time.Ticker, stopCh chan bool) {
 		for {
 			select {
-			case <-g.simulation.ticker.C:
-				if g.simulation.running {
+			case <-ticker.C:
+				g.mu.Lock()
+				if simulation.running {
 					g.stepSimulation()
 				}
-			case <-g.simulation.stopCh:
+				g.mu.Unlock()
+			case <-stopCh:
 				return
 			}
 		}
-	}()
+	}(simulation.ticker, simulation.stopCh)
 }
 
 func (g *GUI) pauseSimulation() {
@@ -231,9 +811,11 @@ func (g *GUI) pauseSimulation() {
 
 	g.simulation.running = false
 	g.startButton.SetText("▶ Start")
+	SetClocksRunning(g.stop, false)
 
 	if g.simulation.ticker != nil {
 		g.simulation.ticker.Stop()
+		close(g.simulation.stopCh)
 	}
 }
 
@@ -248,105 +830,272 @@ func (g *GUI) stepSimulation() {
 	}
 
 	g.world.Simulate()
+	termination, stopped := CheckStop(g.stop, g.world)
+	if stopped {
+		g.world.Log("koniec: " + termination.Reason)
+	}
+	g.timeline.Record(g.world)
+	g.animation.Capture(g.world)
+	g.updateScrubber()
+	start := time.Now()
 	g.updateDisplay()
 	g.updateChart()
-	if g.world.IsExtinct() {
+	g.renderTime = time.Since(start)
+	g.updateDiagnostics()
+	if stopped {
 		g.pauseSimulation()
 	}
 }
 
+func (g *GUI) stepBack() {
+	if g.world == nil {
+		return
+	}
+	g.rewindTo(g.world.Turn - 1)
+}
+
+func (g *GUI) rewindTo(turn int) {
+	world, ok := g.timeline.Restore(turn)
+	if !ok {
+		return
+	}
+	g.pauseSimulation()
+	g.setWorld(world)
+	g.updateScrubber()
+	g.updateDisplay()
+	g.updateChart()
+}
+
+func (g *GUI) updateScrubber() {
+	first, last := g.timeline.Span()
+	g.scrubSlider.Min = float64(first)
+	g.scrubSlider.Max = float64(max(last, first+1))
+	setSliderQuietly(g.scrubSlider, float64(g.world.Turn))
+	options := make([]string, len(g.timeline.Branches))
+	for i, branch := range g.timeline.Branches {
+		options[i] = branch.String()
+	}
+	g.branchList.Options = options
+	g.branchList.Selected = ""
+	g.branchList.Refresh()
+}
+
+func (g *GUI) switchBranch(index int) {
+	branch, ok := g.timeline.SwitchBranch(index)
+	if !ok {
+		return
+	}
+	g.pauseSimulation()
+	for _, snapshot := range branch {
+		g.world.History.Record(snapshot)
+		g.world.Lineage.Record(snapshot)
+		g.world.Trajectories.Record(snapshot)
+		g.world.Occupancy.Record(snapshot)
+		g.animation.Capture(snapshot)
+	}
+	g.setWorld(branch[len(branch)-1].Clone())
+	g.updateScrubber()
+	g.updateDisplay()
+	g.updateChart()
+}
+
 func (g *GUI) updateDisplay() {
 	if g.world == nil {
 		return
 	}
+	g.updateGrid()
+	g.turnLabel.SetText(fmt.Sprintf("Tura: %d", g.world.Turn))
+	g.updateStatistics()
+	logText := ""
+	for i := max(0, len(g.world.EventLog)-8); i < len(g.world.EventLog); i++ {
+		entry := g.world.EventLog[i]
+		logText += fmt.Sprintf("[%d] %s\n", entry.Turn, entry.Message)
+	}
+	g.logLabel.SetText(logText)
+	if g.inspectX.Text != "" && g.inspectY.Text != "" {
+		g.inspectOrganism()
+	}
+}
+
+func (g *GUI) updateStatistics() {
+	demographics := g.world.Demographics()
+	for column, organismType := range placementOrder {
+		stats := demographics.Species[organismType]
+		values := []string{
+			fmt.Sprint(stats.Count),
+			fmt.Sprint(stats.Births),
+			fmt.Sprint(stats.Arrivals),
+			fmt.Sprintf("%d/%d/%d", stats.Causes[EatenDeath], stats.Causes[StarvationDeath], stats.Causes[RemovedDeath]),
+			fmt.Sprintf("%.1f / %.1f", stats.Energy.Mean, stats.Energy.Median),
+			fmt.Sprintf("%d–%d", stats.Energy.Min, stats.Energy.Max),
+			fmt.Sprintf("%.1f", stats.MeanAge),
+			fmt.Sprintf("%.1f%%", stats.Density*100),
+		}
+		for row, value := range values {
+			g.statsCells[row][column].SetText(value)
+		}
+	}
+	g.statsExtra.SetText(fmt.Sprintf("Polowania: %d, wypas: %d\nPokrycie trawą: %.1f%% z %d pól\nWiek lisów: %s\nWiek królików: %s",
+		demographics.PredationEvents, demographics.GrazingEvents,
+		demographics.GrassCover, demographics.HabitableCells,
+		demographics.Species["Fox"].AgeDistribution(), demographics.Species["Rabbit"].AgeDistribution()))
+}
+
+func (g *GUI) analyzeOscillations() {
+	if g.world == nil {
+		return
+	}
+	burnIn, _ := strconv.Atoi(g.burnInEntry.Text)
+	report, err := AnalyzeOscillations(g.world.History.Until(g.world.Turn), burnIn)
+	if err != nil {
+		g.analysis.SetText(err.Error())
+		return
+	}
+	g.analysis.SetText(report.String())
+}
+
+func (g *GUI) fitLotkaVolterra() {
+	if g.world == nil {
+		return
+	}
+	g.pauseSimulation()
+	burnIn, _ := strconv.Atoi(g.burnInEntry.Text)
+	fit, err := FitLotkaVolterra(g.world.History.Until(g.world.Turn), burnIn)
+	if err != nil {
+		g.fit = nil
+		g.analysis.SetText(err.Error())
+	} else {
+		g.fit = &fit
+		g.analysis.SetText(fit.String())
+	}
+	g.updateChart()
+}
+
+func (g *GUI) updateDiagnostics() {
+	if g.world == nil || !g.world.MetricsEnabled() {
+		g.diagLabel.SetText("")
+		return
+	}
+	g.diagLabel.SetText(g.world.LastTurnMetrics().String() +
+		fmt.Sprintf("Renderowanie: %v", g.renderTime.Round(time.Microsecond)))
+}
+
+func (g *GUI) resetViewport() {
+	maxX := max(0, g.world.Width-viewportWidth)
+	maxY := max(0, g.world.Height-viewportHeight)
+	g.viewX, g.viewY = 0, 0
+	g.viewXSlider.Max = float64(max(1, maxX))
+	g.viewYSlider.Max = float64(max(1, maxY))
+	setSliderQuietly(g.viewXSlider, 0)
+	setSliderQuietly(g.viewYSlider, g.viewYSlider.Max)
+	if maxX == 0 {
+		g.viewXSlider.Hide()
+	} else {
+		g.viewXSlider.Show()
+	}
+	if maxY == 0 {
+		g.viewYSlider.Hide()
+	} else {
+		g.viewYSlider.Show()
+	}
+}
+
+func (g *GUI) updateGrid() {
+	if g.world == nil {
+		return
+	}
+	endX := min(g.world.Width, g.viewX+viewportWidth)
+	endY := min(g.world.Height, g.viewY+viewportHeight)
+	trails := make(map[[2]int]bool)
+	if g.trailCheck.Checked && g.world.Trajectories != nil {
+		g.world.ForEachOrganism(func(organism Organism) {
+			for _, point := range g.world.Trajectories.Trail(organism.GetID(), trailLength, g.world.Turn) {
+				trails[[2]int{point.X, point.Y}] = true
+			}
+		})
+	}
+	occupancy := g.world.Occupancy.Until(g.world.Turn)
+	heatSpecies, heatMax := "", 0
+	if species := g.heatmapSpecies(); species != nil {
+		heatSpecies, heatMax = species[0], occupancy.Max(species[0])
+	}
 	gridText := ""
-	for y := 0; y < g.world.Height; y++ {
-		for x := 0; x < g.world.Width; x++ {
-			if g.world.Grid[y][x] != nil {
-				gridText += g.world.Grid[y][x].GetIcon() + " "
+	for y := g.viewY; y < endY; y++ {
+		for x := g.viewX; x < endX; x++ {
+			if heatMax > 0 && g.world.GetTerrain(x, y) != Obstacle {
+				gridText += heatIcon(occupancy.Count(heatSpecies, x, y), heatMax)
+			} else if organism := g.world.GetOrganism(x, y); organism != nil {
+				gridText += organism.GetIcon() + " "
+			} else if trails[[2]int{x, y}] && g.world.GetTerrain(x, y) == Open {
+				gridText += "👣"
 			} else {
-				gridText += "⬜"
+				gridText += g.world.GetTerrain(x, y).GetIcon()
 			}
 		}
 		gridText += "\n"
 	}
 
 	g.gridWidget.ParseMarkdown("```\n" + gridText + "```")
-	stats := g.world.GetStatistics()
-	g.turnLabel.SetText(fmt.Sprintf("Tura: %d", g.world.Turn))
-	g.statsLabel.SetText(fmt.Sprintf("Populacja:\n🦊 Lisy: %d\n🐰 Króliki: %d\n🌱 Trawa: %d\nRazem: %d",
-		stats["Fox"], stats["Rabbit"], stats["Grass"],
-		stats["Fox"]+stats["Rabbit"]+stats["Grass"]))
-	g.turnData = append(g.turnData, float64(g.world.Turn))
-	g.foxData = append(g.foxData, float64(stats["Fox"]))
-	g.rabbitData = append(g.rabbitData, float64(stats["Rabbit"]))
-	g.grassData = append(g.grassData, float64(stats["Grass"]))
-	if len(g.turnData) > 50 {
-		g.turnData = g.turnData[1:]
-		g.foxData = g.foxData[1:]
-		g.rabbitData = g.rabbitData[1:]
-		g.grassData = g.grassData[1:]
+	g.viewLabel.SetText(fmt.Sprintf("Widok: (%d,%d)-(%d,%d) z %dx%d",
+		g.viewX, g.viewY, endX-1, endY-1, g.world.Width, g.world.Height))
+}
+
+func heatIcon(count, top int) string {
+	switch share := float64(count) / float64(top); {
+	case count == 0:
+		return "⬜"
+	case share < 0.25:
+		return "🟨"
+	case share < 0.5:
+		return "🟧"
+	case share < 0.75:
+		return "🟥"
 	}
+	return "🟪"
 }
 
-func (g *GUI) updateChart() {
-	if len(g.turnData) < 1 {
-		g.chartImage.SetResource(nil)
+func (g *GUI) inspectOrganism() {
+	if g.world == nil {
 		return
 	}
-	p := plot.New()
-	p.Title.Text = "Populacja w czasie"
-	p.X.Label.Text = "Tura"
-	p.Y.Label.Text = "Liczba organizmów"
-
-	foxPoints := make(plotter.XYs, len(g.turnData))
-	rabbitPoints := make(plotter.XYs, len(g.turnData))
-	grassPoints := make(plotter.XYs, len(g.turnData))
-
-	for i := range g.turnData {
-		foxPoints[i].X = g.turnData[i]
-		foxPoints[i].Y = g.foxData[i]
-		rabbitPoints[i].X = g.turnData[i]
-		rabbitPoints[i].Y = g.rabbitData[i]
-		grassPoints[i].X = g.turnData[i]
-		grassPoints[i].Y = g.grassData[i]
-	}
-
-	if len(g.turnData) >= 2 {
-		foxLine, _ := plotter.NewLine(foxPoints)
-		foxLine.Color = color.RGBA{R: 255, G: 100, B: 0, A: 255}
-		foxLine.Width = vg.Points(2)
-
-		rabbitLine, _ := plotter.NewLine(rabbitPoints)
-		rabbitLine.Color = color.RGBA{R: 139, G: 69, B: 19, A: 255}
-		rabbitLine.Width = vg.Points(2)
-
-		grassLine, _ := plotter.NewLine(grassPoints)
-		grassLine.Color = color.RGBA{R: 0, G: 128, B: 0, A: 255}
-		grassLine.Width = vg.Points(2)
-
-		p.Add(foxLine, rabbitLine, grassLine)
-		p.Legend.Add("🦊 Lisy", foxLine)
-		p.Legend.Add("🐰 Króliki", rabbitLine)
-		p.Legend.Add("🌱 Trawa", grassLine)
-	} else {
-		foxScatter, _ := plotter.NewScatter(foxPoints)
-		foxScatter.Color = color.RGBA{R: 255, G: 100, B: 0, A: 255}
-
-		rabbitScatter, _ := plotter.NewScatter(rabbitPoints)
-		rabbitScatter.Color = color.RGBA{R: 139, G: 69, B: 19, A: 255}
-
-		grassScatter, _ := plotter.NewScatter(grassPoints)
-		grassScatter.Color = color.RGBA{R: 0, G: 128, B: 0, A: 255}
+	x, errX := strconv.Atoi(g.inspectX.Text)
+	y, errY := strconv.Atoi(g.inspectY.Text)
+	if errX != nil || errY != nil || !g.world.IsValidPosition(x, y) {
+		g.inspectInfo.SetText("Niepoprawna pozycja")
+		return
+	}
+	organism := g.world.GetOrganism(x, y)
+	if organism == nil {
+		g.inspectInfo.SetText(fmt.Sprintf("(%d,%d): puste pole", x, y))
+		return
+	}
+	food, danger := 0, 0
+	for _, entry := range organism.GetMemory().Entries() {
+		if entry.Kind == FoodMemory {
+			food++
+		} else {
+			danger++
+		}
+	}
+	lineage := ""
+	if node, ok := g.world.Lineage.Nodes[organism.GetID()]; ok {
+		lineage = fmt.Sprintf("\nUrodzony: tura %d, rodzice: %v\nZałożyciele linii: %v", node.BirthTurn, node.Parents, node.Founders)
+	}
+	g.inspectInfo.SetText(fmt.Sprintf("%s ID: %d\nEnergia: %d\nStan: %s\nPunkty akcji: %d\nPamięć: %d jedzenie, %d zagrożenie%s",
+		organism.GetIcon(), organism.GetID(), organism.GetEnergy(),
+		organism.GetState(), organism.GetActionPoints(), food, danger, lineage))
+}
 
-		p.Add(foxScatter, rabbitScatter, grassScatter)
-		p.Legend.Add("🦊 Lisy", foxScatter)
-		p.Legend.Add("🐰 Króliki", rabbitScatter)
-		p.Legend.Add("🌱 Trawa", grassScatter)
+func (g *GUI) updateChart() {
+	records := g.world.History.Window(chartWindow, g.world.Turn)
+	if len(records) < 1 {
+		g.chartImage.SetResource(nil)
+		return
 	}
+	chart := NewPopulationChart(records)
+	AddFitOverlay(chart, g.fit, records)
 	img := vgimg.New(vg.Points(1200), vg.Points(900))
-	dc := draw.New(img)
-	p.Draw(dc)
+	chart.Draw(draw.New(img))
 	var buf bytes.Buffer
 	png.Encode(&buf, img.Image())
 	resource := fyne.NewStaticResource("chart.png", buf.Bytes())
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type TurnRecord struct {
+	Turn       int                `json:"turn"`
+	Counts     map[string]int     `json:"counts"`
+	Births     map[string]int     `json:"births"`
+	Arrivals   map[string]int     `json:"arrivals"`
+	Deaths     map[string]int     `json:"deaths"`
+	MeanEnergy map[string]float64 `json:"mean_energy"`
+}
+
+type History struct {
+	Records []TurnRecord
+}
+
+func NewHistory() *History {
+	return &History{}
+}
+
+func (h *History) Record(w *World) {
+	if h == nil {
+		return
+	}
+	for len(h.Records) > 0 && h.Records[len(h.Records)-1].Turn >= w.Turn {
+		h.Records = h.Records[:len(h.Records)-1]
+	}
+
+	record := TurnRecord{
+		Turn:       w.Turn,
+		Counts:     make(map[string]int),
+		Births:     make(map[string]int),
+		Arrivals:   make(map[string]int),
+		Deaths:     make(map[string]int),
+		MeanEnergy: make(map[string]float64),
+	}
+	for _, organismType := range placementOrder {
+		record.Counts[organismType] = w.CountOrganisms(organismType)
+		record.Births[organismType] = w.births[organismType]
+		record.Arrivals[organismType] = w.arrivals[organismType]
+		record.Deaths[organismType] = w.deaths[organismType]
+		organisms := w.index.ByType(organismType)
+		if len(organisms) == 0 {
+			record.MeanEnergy[organismType] = 0
+			continue
+		}
+		total := 0
+		for _, organism := range organisms {
+			total += organism.GetEnergy()
+		}
+		record.MeanEnergy[organismType] = float64(total) / float64(len(organisms))
+	}
+	h.Records = append(h.Records, record)
+}
+
+func (h *History) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.Records)
+}
+
+func (h *History) Until(turn int) []TurnRecord {
+	if h == nil {
+		return nil
+	}
+	end := sort.Search(len(h.Records), func(i int) bool { return h.Records[i].Turn > turn })
+	return h.Records[:end]
+}
+
+func (h *History) WriteCSV(out io.Writer) error {
+	writer := csv.NewWriter(out)
+	header := []string{"turn"}
+	for _, organismType := range placementOrder {
+		name := strings.ToLower(organismType)
+		header = append(header, name, name+"_births", name+"_arrivals", name+"_deaths", name+"_energy")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, record := range h.Records {
+		row := []string{strconv.Itoa(record.Turn)}
+		for _, organismType := range placementOrder {
+			row = append(row,
+				strconv.Itoa(record.Counts[organismType]),
+				strconv.Itoa(record.Births[organismType]),
+				strconv.Itoa(record.Arrivals[organismType]),
+				strconv.Itoa(record.Deaths[organismType]),
+				strconv.FormatFloat(record.MeanEnergy[organismType], 'f', 3, 64),
+			)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (h *History) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(h.Records)
+}
+
+func (h *History) Export(out io.Writer, format string) error {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "csv":
+		return h.WriteCSV(out)
+	case "json":
+		return h.WriteJSON(out)
+	}
+	return fmt.Errorf("nieznany format eksportu %q", format)
+}
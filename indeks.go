@@ -0,0 +1,180 @@
+package main
+
+const bucketSize = 8
+
+type bucketKey struct {
+	bx, by int
+}
+
+type organismSet struct {
+	items []Organism
+	pos   map[int]int
+}
+
+func newOrganismSet() *organismSet {
+	return &organismSet{pos: make(map[int]int)}
+}
+
+func (s *organismSet) add(organism Organism) {
+	s.pos[organism.GetID()] = len(s.items)
+	s.items = append(s.items, organism)
+}
+
+func (s *organismSet) remove(organism Organism) {
+	i, ok := s.pos[organism.GetID()]
+	if !ok {
+		return
+	}
+	last := len(s.items) - 1
+	s.items[i] = s.items[last]
+	s.pos[s.items[i].GetID()] = i
+	s.items[last] = nil
+	s.items = s.items[:last]
+	delete(s.pos, organism.GetID())
+}
+
+func (s *organismSet) cloneWith(clones map[int]Organism) *organismSet {
+	clone := &organismSet{
+		items: make([]Organism, len(s.items)),
+		pos:   make(map[int]int, len(s.pos)),
+	}
+	for i, organism := range s.items {
+		clone.items[i] = clones[organism.GetID()]
+		clone.pos[organism.GetID()] = i
+	}
+	return clone
+}
+
+type OrganismIndex struct {
+	all     *organismSet
+	counts  map[string]int
+	byType  map[string]*organismSet
+	buckets map[bucketKey][]Organism
+}
+
+func NewOrganismIndex() *OrganismIndex {
+	return &OrganismIndex{
+		all:     newOrganismSet(),
+		counts:  map[string]int{"Fox": 0, "Rabbit": 0, "Grass": 0},
+		byType:  make(map[string]*organismSet),
+		buckets: make(map[bucketKey][]Organism),
+	}
+}
+
+func (idx *OrganismIndex) cloneWith(clones map[int]Organism) *OrganismIndex {
+	clone := &OrganismIndex{
+		all:     idx.all.cloneWith(clones),
+		counts:  idx.Counts(),
+		byType:  make(map[string]*organismSet, len(idx.byType)),
+		buckets: make(map[bucketKey][]Organism, len(idx.buckets)),
+	}
+	for organismType, set := range idx.byType {
+		clone.byType[organismType] = set.cloneWith(clones)
+	}
+	for key, bucket := range idx.buckets {
+		cloned := make([]Organism, len(bucket))
+		for i, organism := range bucket {
+			cloned[i] = clones[organism.GetID()]
+		}
+		clone.buckets[key] = cloned
+	}
+	return clone
+}
+
+func bucketOf(x, y int) bucketKey {
+	return bucketKey{x / bucketSize, y / bucketSize}
+}
+
+func (idx *OrganismIndex) Add(organism Organism) {
+	organismType := organism.GetType()
+	idx.all.add(organism)
+	idx.counts[organismType]++
+	set, ok := idx.byType[organismType]
+	if !ok {
+		set = newOrganismSet()
+		idx.byType[organismType] = set
+	}
+	set.add(organism)
+	key := bucketOf(organism.GetPosition())
+	idx.buckets[key] = append(idx.buckets[key], organism)
+}
+
+func (idx *OrganismIndex) Remove(organism Organism) {
+	idx.all.remove(organism)
+	idx.counts[organism.GetType()]--
+	if set, ok := idx.byType[organism.GetType()]; ok {
+		set.remove(organism)
+	}
+	idx.removeFromBucket(bucketOf(organism.GetPosition()), organism)
+}
+
+func (idx *OrganismIndex) Move(organism Organism, fromX, fromY int) {
+	from := bucketOf(fromX, fromY)
+	to := bucketOf(organism.GetPosition())
+	if from == to {
+		return
+	}
+	idx.removeFromBucket(from, organism)
+	idx.buckets[to] = append(idx.buckets[to], organism)
+}
+
+func (idx *OrganismIndex) removeFromBucket(key bucketKey, organism Organism) {
+	bucket := idx.buckets[key]
+	for i, other := range bucket {
+		if other == organism {
+			bucket = append(bucket[:i], bucket[i+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(idx.buckets, key)
+		return
+	}
+	idx.buckets[key] = bucket
+}
+
+func (idx *OrganismIndex) Count(organismType string) int {
+	return idx.counts[organismType]
+}
+
+func (idx *OrganismIndex) Counts() map[string]int {
+	counts := make(map[string]int, len(idx.counts))
+	for organismType, count := range idx.counts {
+		counts[organismType] = count
+	}
+	return counts
+}
+
+func (idx *OrganismIndex) All() []Organism {
+	return idx.all.items
+}
+
+func (idx *OrganismIndex) ByType(organismType string) []Organism {
+	if set, ok := idx.byType[organismType]; ok {
+		return set.items
+	}
+	return nil
+}
+
+func (idx *OrganismIndex) InRadius(x, y, radius int, visit func(Organism)) {
+	minBucket := bucketOf(max(0, x-radius), max(0, y-radius))
+	maxBucket := bucketOf(x+radius, y+radius)
+	for by := minBucket.by; by <= maxBucket.by; by++ {
+		for bx := minBucket.bx; bx <= maxBucket.bx; bx++ {
+			for _, organism := range idx.buckets[bucketKey{bx, by}] {
+				ox, oy := organism.GetPosition()
+				if squaredDistance(x, y, ox, oy) <= radius*radius {
+					visit(organism)
+				}
+			}
+		}
+	}
+}
+
+func (w *World) OrganismsInRadius(x, y, radius int) []Organism {
+	var organisms []Organism
+	w.index.InRadius(x, y, radius, func(organism Organism) {
+		organisms = append(organisms, organism)
+	})
+	return organisms
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type HeadlessOptions struct {
+	Width, Height         int
+	Foxes, Rabbits, Grass int
+	Obstacles, Hedgerows  int
+	Turns                 int
+	Seed                  int64
+	MapPath, ScenarioPath string
+	UpdateMode            UpdateMode
+	ConflictRule          ConflictRule
+	Workers               int
+	Placement             PlacementStrategy
+	Stop                  string
+	CSVPath, JSONPath     string
+	ArchivePath           string
+	GIFPath, APNGPath     string
+	Animation             AnimationOptions
+	ChartPath             string
+	FramesDir             string
+	LineagePath           string
+	TrajectoryPath        string
+	TrackedIDs            []int
+	HeatmapPath           string
+	HeatmapWindow         int
+	HeatmapSpecies        []string
+	Analysis              bool
+	FitLV                 bool
+	BurnIn                int
+	FrameEvery            int
+	ChartDPI              int
+}
+
+func buildHeadlessWorld(options HeadlessOptions) (*World, error) {
+	var w *World
+	if options.MapPath != "" {
+		loaded, err := LoadMap(options.MapPath)
+		if err != nil {
+			return nil, err
+		}
+		w = loaded
+	} else {
+		if options.Width < 1 || options.Height < 1 {
+			return nil, fmt.Errorf("niepoprawny rozmiar świata %dx%d", options.Width, options.Height)
+		}
+		for _, count := range []struct {
+			name  string
+			value int
+		}{
+			{"lisów", options.Foxes},
+			{"królików", options.Rabbits},
+			{"trawy", options.Grass},
+			{"przeszkód", options.Obstacles},
+			{"żywopłotów", options.Hedgerows},
+		} {
+			if count.value < 0 {
+				return nil, fmt.Errorf("ujemna liczba %s: %d", count.name, count.value)
+			}
+		}
+		w = NewWorld(options.Width, options.Height)
+	}
+	if options.Seed != 0 {
+		w.SetSeed(options.Seed)
+	}
+	w.UpdateMode = options.UpdateMode
+	w.ConflictRule = options.ConflictRule
+	w.Workers = max(1, options.Workers)
+	if options.ScenarioPath != "" {
+		scenario, err := LoadScenario(options.ScenarioPath)
+		if err != nil {
+			return nil, err
+		}
+		w.Scenario = scenario
+	}
+	if options.MapPath == "" {
+		w.GenerateTerrain(options.Obstacles, options.Hedgerows)
+		w.Populate(options.Placement, map[string]int{
+			"Fox":    options.Foxes,
+			"Rabbit": options.Rabbits,
+			"Grass":  options.Grass,
+		})
+	}
+	return w, nil
+}
+
+func RunHeadless(options HeadlessOptions, out io.Writer) error {
+	stop, err := ParseStopConditions(options.Stop)
+	if err != nil {
+		return err
+	}
+	stop = append(stop, TurnLimit{Turns: options.Turns})
+	started := time.Now()
+	w, err := buildHeadlessWorld(options)
+	if err != nil {
+		return err
+	}
+	w.History = NewHistory()
+	w.History.Record(w)
+	if options.LineagePath != "" {
+		w.Lineage = NewLineage()
+		w.Lineage.Record(w)
+	}
+	if options.TrajectoryPath != "" {
+		w.Trajectories = NewTrajectories(options.TrackedIDs...)
+		w.Trajectories.Record(w)
+	}
+	if options.HeatmapPath != "" {
+		w.Occupancy = NewOccupancy(options.HeatmapWindow)
+		w.Occupancy.Record(w)
+	}
+	var animation *Animation
+	if options.GIFPath != "" || options.APNGPath != "" {
+		animation = NewAnimation(options.Animation)
+		animation.Capture(w)
+	}
+	if options.FramesDir != "" {
+		if err := os.MkdirAll(options.FramesDir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := saveHeadlessFrame(options, w); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "# seed %d, %dx%d\n", w.Seed, w.Width, w.Height)
+	fmt.Fprintln(out, "turn\tfox\trabbit\tgrass")
+	logged := 0
+	var termination Termination
+	for {
+		var done bool
+		termination, done = CheckStop(stop, w)
+		for ; logged < len(w.EventLog); logged++ {
+			fmt.Fprintf(out, "# [%d] %s\n", w.EventLog[logged].Turn, w.EventLog[logged].Message)
+		}
+		if done {
+			fmt.Fprintf(out, "# koniec: %s\n", termination)
+			break
+		}
+		w.Simulate()
+		animation.Capture(w)
+		if err := saveHeadlessFrame(options, w); err != nil {
+			return err
+		}
+		stats := w.GetStatistics()
+		fmt.Fprintf(out, "%d\t%d\t%d\t%d\n", w.Turn, stats["Fox"], stats["Rabbit"], stats["Grass"])
+	}
+	if options.Analysis {
+		report, err := AnalyzeOscillations(w.History.Records, options.BurnIn)
+		if err != nil {
+			fmt.Fprintf(out, "# analiza cykli: %v\n", err)
+		} else {
+			for _, line := range strings.Split(strings.TrimSpace(report.String()), "\n") {
+				fmt.Fprintf(out, "# %s\n", line)
+			}
+		}
+	}
+	var fit *LotkaVolterraFit
+	if options.FitLV {
+		result, err := FitLotkaVolterra(w.History.Records, options.BurnIn)
+		if err != nil {
+			fmt.Fprintf(out, "# dopasowanie Lotka-Volterra: %v\n", err)
+		} else {
+			fit = &result
+			for _, line := range strings.Split(strings.TrimSpace(result.String()), "\n") {
+				fmt.Fprintf(out, "# %s\n", line)
+			}
+		}
+	}
+	exports := []struct {
+		path   string
+		format string
+		export func(io.Writer, string) error
+	}{
+		{options.CSVPath, "csv", w.History.Export},
+		{options.JSONPath, "json", w.History.Export},
+		{options.GIFPath, "gif", animation.Export},
+		{options.APNGPath, "apng", animation.Export},
+		{options.LineagePath, filepath.Ext(options.LineagePath), w.Lineage.Export},
+		{options.TrajectoryPath, filepath.Ext(options.TrajectoryPath), w.Trajectories.Export},
+		{options.HeatmapPath, filepath.Ext(options.HeatmapPath), func(out io.Writer, format string) error {
+			return w.Occupancy.Export(out, format, options.HeatmapSpecies)
+		}},
+		{options.ChartPath, filepath.Ext(options.ChartPath), func(out io.Writer, format string) error {
+			chart := NewPopulationChart(w.History.Records)
+			AddFitOverlay(chart, fit, w.History.Records)
+			return WriteChart(chart, out, format, options.ChartDPI)
+		}},
+	}
+	for _, e := range exports {
+		if err := exportFile(e.path, e.format, e.export); err != nil {
+			return err
+		}
+	}
+	if options.ArchivePath == "" {
+		return nil
+	}
+	archive, err := OpenArchive(options.ArchivePath)
+	if err != nil {
+		return err
+	}
+	id, err := archive.Save(newRunRecord(options, w, termination, started))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "# zapisano przebieg #%d w %s\n", id, options.ArchivePath)
+	return nil
+}
+
+func newRunRecord(options HeadlessOptions, w *World, termination Termination, started time.Time) RunRecord {
+	return RunRecord{
+		Started:      started,
+		Duration:     time.Since(started),
+		Width:        w.Width,
+		Height:       w.Height,
+		Foxes:        options.Foxes,
+		Rabbits:      options.Rabbits,
+		Grass:        options.Grass,
+		Obstacles:    options.Obstacles,
+		Hedgerows:    options.Hedgerows,
+		MaxTurns:     options.Turns,
+		Seed:         w.Seed,
+		Map:          options.MapPath,
+		Scenario:     options.ScenarioPath,
+		UpdateMode:   options.UpdateMode,
+		ConflictRule: options.ConflictRule,
+		Workers:      options.Workers,
+		Placement:    PlacementKeys[options.Placement],
+		Stop:         options.Stop,
+		Reason:       termination.Reason,
+		Turns:        termination.Turn,
+		Final:        w.GetStatistics(),
+		History:      w.History.Records,
+	}
+}
+
+func ListRuns(path, where string, out io.Writer) error {
+	filter, err := ParseRunFilter(where)
+	if err != nil {
+		return err
+	}
+	archive, err := OpenArchive(path)
+	if err != nil {
+		return err
+	}
+	for _, run := range archive.List(filter) {
+		fmt.Fprintln(out, run)
+	}
+	return nil
+}
+
+func ShowRun(path string, id int, out io.Writer) error {
+	archive, err := OpenArchive(path)
+	if err != nil {
+		return err
+	}
+	run, ok := archive.Get(id)
+	if !ok {
+		return fmt.Errorf("brak przebiegu #%d w %s", id, path)
+	}
+	fmt.Fprintf(out, "# %s\n", run)
+	history := History{Records: run.History}
+	return history.WriteCSV(out)
+}
+
+func saveHeadlessFrame(options HeadlessOptions, w *World) error {
+	if options.FramesDir == "" || w.Turn%max(1, options.FrameEvery) != 0 {
+		return nil
+	}
+	path := filepath.Join(options.FramesDir, fmt.Sprintf("tura_%06d.png", w.Turn))
+	renderer := options.Animation.Renderer
+	renderer.MaxSize = maxImageSize
+	return SaveFrame(renderer, w, path)
+}
+
+func exportFile(path, format string, export func(io.Writer, string) error) error {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export(file, format); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
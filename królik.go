@@ -3,6 +3,7 @@ package main
 import "fmt"
 
 type Rabbit struct {
+	actor
 	energy           int
 	ID               int
 	icon             string
@@ -19,6 +20,7 @@ type Rabbit struct {
 
 func NewRabbit(id int, x int, y int) *Rabbit {
 	return &Rabbit{
+		actor:            newActor("Rabbit"),
 		ID:               id,
 		ate:              false,
 		energy:           10,
@@ -36,7 +38,7 @@ func NewRabbit(id int, x int, y int) *Rabbit {
 }
 
 func (r *Rabbit) PrintInfo() {
-	fmt.Printf("ID: %d\nEnergy: %d\nPosition: (%d,%d)\nAte: %t\nCan Breed: %t\n", r.ID, r.energy, r.x, r.y, r.ate, r.canBreed)
+	fmt.Printf("ID: %d\nEnergy: %d\nPosition: (%d,%d)\nAte: %t\nCan Breed: %t\nState: %s\n", r.ID, r.energy, r.x, r.y, r.ate, r.canBreed, r.state)
 }
 
 func (r *Rabbit) GetIcon() string {
@@ -100,16 +102,26 @@ func (r *Rabbit) Breed() {
 	}
 }
 
+func (r *Rabbit) Clone() Organism {
+	clone := *r
+	clone.actor = r.actor.clone()
+	return &clone
+}
+
 func (r *Rabbit) Move(x int, y int) {
 	r.x = x
 	r.y = y
 }
+func (r *Rabbit) UseEnergy(amount int) {
+	r.energy -= amount
+}
 func (r *Rabbit) Die() {
 	r.energy = 0
 	r.canMove = false
 }
 
 func (r *Rabbit) NewTurn() {
+	r.startTurn()
 	if r.eatingCooldown > 0 {
 		r.eatingCooldown--
 	}
@@ -3,6 +3,7 @@ package main
 import "fmt"
 
 type Fox struct {
+	actor
 	energy           int
 	ID               int
 	icon             string
@@ -19,6 +20,7 @@ type Fox struct {
 
 func NewFox(id int, x int, y int) *Fox {
 	return &Fox{
+		actor:            newActor("Fox"),
 		ID:               id,
 		canEat:           true,
 		ate:              false,
@@ -36,7 +38,7 @@ func NewFox(id int, x int, y int) *Fox {
 }
 
 func (r *Fox) PrintInfo() {
-	fmt.Printf("ID: %d\nEnergy: %d\nPosition: (%d,%d)\nAte: %t\nCan Breed: %t\n", r.ID, r.energy, r.x, r.y, r.ate, r.canBreed)
+	fmt.Printf("ID: %d\nEnergy: %d\nPosition: (%d,%d)\nAte: %t\nCan Breed: %t\nState: %s\n", r.ID, r.energy, r.x, r.y, r.ate, r.canBreed, r.state)
 }
 
 func (r *Fox) GetIcon() string {
@@ -100,16 +102,26 @@ func (r *Fox) Breed() {
 	}
 }
 
+func (r *Fox) Clone() Organism {
+	clone := *r
+	clone.actor = r.actor.clone()
+	return &clone
+}
+
 func (r *Fox) Move(x int, y int) {
 	r.x = x
 	r.y = y
 }
+func (r *Fox) UseEnergy(amount int) {
+	r.energy -= amount
+}
 func (r *Fox) Die() {
 	r.energy = 0
 	r.canMove = false
 }
 
 func (r *Fox) NewTurn() {
+	r.startTurn()
 	if r.eatingCooldown > 0 {
 		r.eatingCooldown--
 	}
@@ -0,0 +1,44 @@
+package main
+
+type randSource interface {
+	Intn(n int) int
+	Float64() float64
+}
+
+type splitMix struct {
+	state uint64
+}
+
+func newSplitMix(seeds ...int64) *splitMix {
+	s := &splitMix{}
+	for _, seed := range seeds {
+		s.state ^= uint64(seed)
+		s.next()
+	}
+	return s
+}
+
+func (s *splitMix) next() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	return int(s.next() % uint64(n))
+}
+
+func (s *splitMix) Float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
+
+func (s *splitMix) Shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, s.Intn(i+1))
+	}
+}
@@ -1,6 +1,128 @@
 package main
 
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
 func main() {
+	var options HeadlessOptions
+	headless := flag.Bool("headless", false, "uruchom symulację bez okna")
+	flag.IntVar(&options.Width, "width", 20, "szerokość świata")
+	flag.IntVar(&options.Height, "height", 15, "wysokość świata")
+	flag.IntVar(&options.Foxes, "foxes", 5, "początkowa liczba lisów")
+	flag.IntVar(&options.Rabbits, "rabbits", 15, "początkowa liczba królików")
+	flag.IntVar(&options.Grass, "grass", 50, "początkowa liczba kępek trawy")
+	flag.IntVar(&options.Obstacles, "obstacles", 10, "liczba przeszkód")
+	flag.IntVar(&options.Hedgerows, "hedgerows", 4, "liczba żywopłotów")
+	flag.IntVar(&options.Turns, "turns", 500, "maksymalna liczba tur")
+	flag.Int64Var(&options.Seed, "seed", 0, "ziarno generatora (0 = losowe)")
+	flag.StringVar(&options.MapPath, "map", "", "plik z początkowym układem świata")
+	flag.StringVar(&options.ScenarioPath, "scenario", "", "plik scenariusza")
+	mode := flag.Int("mode", 0, "tryb aktualizacji: 0 sekwencyjny, 1 stała kolejność, 2 synchroniczny")
+	rule := flag.Int("conflicts", 0, "rozstrzyganie konfliktów: 0 losowo, 1 najniższe ID, 2 najwięcej energii")
+	flag.IntVar(&options.Workers, "workers", 1, "liczba wątków w trybie synchronicznym")
+	placement := flag.String("placement", "uniform", "rozmieszczenie: "+strings.Join(PlacementKeys, ", "))
+	flag.StringVar(&options.Stop, "stop", "allextinct", "warunki stopu, np. extinct:Fox,steady:50:0.05,time:1m")
+	flag.StringVar(&options.CSVPath, "csv", "", "plik CSV z pełną historią populacji")
+	flag.StringVar(&options.JSONPath, "json", "", "plik JSON z pełną historią populacji")
+	flag.StringVar(&options.ArchivePath, "archive", "", "plik archiwum przebiegów (dopisuje bieżący przebieg)")
+	flag.StringVar(&options.GIFPath, "gif", "", "plik animacji GIF")
+	flag.StringVar(&options.APNGPath, "apng", "", "plik animacji APNG")
+	options.Animation = DefaultAnimationOptions()
+	flag.IntVar(&options.Animation.CellSize, "cell", options.Animation.CellSize, "rozmiar komórki na obrazach (piksele)")
+	flag.IntVar(&options.Animation.FrameSkip, "frame-skip", options.Animation.FrameSkip, "zapisuj co N-tą turę w animacji")
+	flag.IntVar(&options.Animation.Delay, "frame-delay", options.Animation.Delay, "czas wyświetlania klatki (setne sekundy)")
+	flag.BoolVar(&options.Animation.ShowTurn, "turn-counter", options.Animation.ShowTurn, "pokazuj numer tury na klatkach")
+	flag.BoolVar(&options.Animation.GridLines, "grid-lines", false, "rysuj linie siatki na obrazach")
+	flag.BoolVar(&options.Animation.EnergyShading, "energy-shading", false, "cieniuj organizmy według energii")
+	sprites := flag.String("sprites", "", "katalog z obrazkami Fox.png, Rabbit.png, Grass.png")
+	flag.StringVar(&options.FramesDir, "frames", "", "katalog na klatki PNG")
+	flag.IntVar(&options.FrameEvery, "frame-every", 1, "zapisuj klatkę PNG co N tur")
+	colors := flag.String("colors", "", "kolory na obrazach, np. Fox=#ff8000,Grass=#00aa00")
+	flag.StringVar(&options.ChartPath, "chart", "", "plik wykresu całej historii (.svg, .pdf, .eps lub .png)")
+	flag.IntVar(&options.ChartDPI, "chart-dpi", 300, "rozdzielczość wykresu PNG")
+	flag.StringVar(&options.LineagePath, "lineage", "", "plik rodowodu lisów i królików (.dot, .nwk lub .tsv z liczbą linii założycieli)")
+	flag.StringVar(&options.TrajectoryPath, "trajectories", "", "plik trajektorii (.csv lub .geojson)")
+	track := flag.String("track", "", "identyfikatory śledzonych organizmów, np. 3,7,12 (puste = wszystkie lisy i króliki)")
+	flag.StringVar(&options.HeatmapPath, "heatmap", "", "plik mapy cieplnej obecności (.csv lub .png)")
+	flag.IntVar(&options.HeatmapWindow, "heatmap-window", 0, "liczba ostatnich tur w mapie cieplnej (0 = cały przebieg)")
+	heatmapSpecies := flag.String("heatmap-species", "", "gatunki na obrazie mapy cieplnej, np. Fox,Rabbit (puste = wszystkie)")
+	flag.BoolVar(&options.Analysis, "analysis", false, "wypisz analizę cykli drapieżnik-ofiara na końcu przebiegu")
+	flag.BoolVar(&options.FitLV, "lv", false, "dopasuj model Lotka-Volterra i nanieś go na wykres (-chart)")
+	flag.IntVar(&options.BurnIn, "burn-in", 0, "liczba początkowych tur pomijanych w analizie i dopasowaniu")
+	listRuns := flag.Bool("list-runs", false, "wypisz przebiegi z archiwum zamiast uruchamiać symulację")
+	where := flag.String("where", "", "filtr przebiegów, np. \"foxes>3,grid=40x40,turns>=500,final_fox>0\"")
+	showRun := flag.Int("show-run", 0, "wypisz historię przebiegu o podanym numerze")
+	flag.Parse()
+
+	if *listRuns || *showRun > 0 {
+		path := options.ArchivePath
+		if path == "" {
+			path = "przebiegi.jsonl"
+		}
+		var err error
+		if *listRuns {
+			err = ListRuns(path, *where, os.Stdout)
+		} else {
+			err = ShowRun(path, *showRun, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *headless {
+		if *mode < 0 || *mode >= len(UpdateModeNames) {
+			fmt.Fprintf(os.Stderr, "nieznany tryb aktualizacji %d (0–%d)\n", *mode, len(UpdateModeNames)-1)
+			os.Exit(1)
+		}
+		if *rule < 0 || *rule >= len(ConflictRuleNames) {
+			fmt.Fprintf(os.Stderr, "nieznana reguła konfliktów %d (0–%d)\n", *rule, len(ConflictRuleNames)-1)
+			os.Exit(1)
+		}
+		options.UpdateMode = UpdateMode(*mode)
+		options.ConflictRule = ConflictRule(*rule)
+		strategy, err := ParsePlacement(*placement)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		options.Placement = strategy
+		if options.Animation.Colors, err = ParseColors(*colors); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if options.TrackedIDs, err = ParseTrajectoryIDs(*track); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *heatmapSpecies != "" {
+			options.HeatmapSpecies = strings.Split(*heatmapSpecies, ",")
+			for _, species := range options.HeatmapSpecies {
+				if NewOrganism(species, 0, 0, 0) == nil {
+					fmt.Fprintf(os.Stderr, "nieznany gatunek %q\n", species)
+					os.Exit(1)
+				}
+			}
+		}
+		if *sprites != "" {
+			if options.Animation.Sprites, err = LoadSprites(*sprites); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		if err := RunHeadless(options, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	gui := NewGUI()
 	gui.Run()
 }
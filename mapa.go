@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var mapSymbols = map[rune]struct {
+	organismType string
+	terrain      Terrain
+}{
+	'F': {"Fox", Open},
+	'R': {"Rabbit", Open},
+	'G': {"Grass", Open},
+	'f': {"Fox", Cover},
+	'r': {"Rabbit", Cover},
+	'g': {"Grass", Cover},
+	'.': {"", Open},
+	'#': {"", Obstacle},
+	'%': {"", Cover},
+	'🦊': {"Fox", Open},
+	'🐰': {"Rabbit", Open},
+	'🌱': {"Grass", Open},
+	'⬜': {"", Open},
+	'🪨': {"", Obstacle},
+	'🌳': {"", Cover},
+}
+
+func LoadMap(path string) (*World, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseMap(file)
+}
+
+func ParseMap(r io.Reader) (*World, error) {
+	var rows [][]rune
+	var overrides [][2]string
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(text, "//") || strings.HasPrefix(text, ";") {
+			continue
+		}
+		if fields := strings.Fields(text); len(fields) > 0 && fields[0] == "energy" {
+			overrides = append(overrides, [2]string{strconv.Itoa(line), text})
+			continue
+		}
+		if text == "" {
+			if len(rows) > 0 {
+				rows = append(rows, nil)
+			}
+			continue
+		}
+		var row []rune
+		previous := rune(0)
+		for _, symbol := range text {
+			if symbol == ' ' && previous > 127 {
+				previous = symbol
+				continue
+			}
+			if _, ok := mapSymbols[symbol]; !ok {
+				return nil, fmt.Errorf("linia %d: nieznany symbol %q", line, symbol)
+			}
+			row = append(row, symbol)
+			previous = symbol
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for len(rows) > 0 && rows[len(rows)-1] == nil {
+		rows = rows[:len(rows)-1]
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("mapa jest pusta")
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	w := NewWorld(width, len(rows))
+	for y, row := range rows {
+		for x, symbol := range row {
+			cell := mapSymbols[symbol]
+			w.SetTerrain(x, y, cell.terrain)
+			if cell.organismType != "" {
+				w.PlaceOrganism(NewOrganism(cell.organismType, w.nextID, x, y))
+				w.nextID++
+			}
+		}
+	}
+
+	for _, override := range overrides {
+		var x, y, energy int
+		if _, err := fmt.Sscanf(override[1], "energy %d %d %d", &x, &y, &energy); err != nil {
+			return nil, fmt.Errorf("linia %s: użycie: energy <x> <y> <wartość>", override[0])
+		}
+		organism := w.GetOrganism(x, y)
+		if organism == nil {
+			return nil, fmt.Errorf("linia %s: brak organizmu na (%d,%d)", override[0], x, y)
+		}
+		organism.UseEnergy(organism.GetEnergy() - energy)
+	}
+	return w, nil
+}
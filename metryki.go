@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type Phase int
+
+const (
+	PerceptionPhase Phase = iota
+	FeedingPhase
+	MovementPhase
+	BreedingPhase
+	ConflictPhase
+	CleanupPhase
+	GrassPhase
+	phaseCount
+)
+
+var PhaseNames = [phaseCount]string{"Percepcja", "Żerowanie", "Ruch", "Rozmnażanie", "Konflikty", "Aktualizacja", "Trawa"}
+
+type Counter int
+
+const (
+	Decisions Counter = iota
+	PerceptionQueries
+	PartnerSearches
+	NeighborQueries
+	RejectedIntents
+	counterCount
+)
+
+var CounterNames = [counterCount]string{"Decyzje", "Zapytania percepcji", "Szukanie partnera", "Zapytania o sąsiadów", "Odrzucone zamiary"}
+
+type TurnMetrics struct {
+	Turn     int
+	Total    time.Duration
+	Phases   [phaseCount]time.Duration
+	Counters [counterCount]int64
+}
+
+func (m TurnMetrics) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tura %d: %v\n", m.Turn, m.Total.Round(time.Microsecond))
+	for phase, duration := range m.Phases {
+		fmt.Fprintf(&b, "%s: %v\n", PhaseNames[phase], duration.Round(time.Microsecond))
+	}
+	for counter, value := range m.Counters {
+		fmt.Fprintf(&b, "%s: %d\n", CounterNames[counter], value)
+	}
+	return b.String()
+}
+
+type metricsRecorder struct {
+	phases   [phaseCount]atomic.Int64
+	counters [counterCount]atomic.Int64
+}
+
+func (m *metricsRecorder) start() time.Time {
+	if m == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (m *metricsRecorder) stop(phase Phase, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.phases[phase].Add(int64(time.Since(start)))
+}
+
+func (m *metricsRecorder) count(counter Counter) {
+	if m == nil {
+		return
+	}
+	m.counters[counter].Add(1)
+}
+
+func (m *metricsRecorder) collect(turn int, start time.Time) TurnMetrics {
+	metrics := TurnMetrics{Turn: turn, Total: time.Since(start)}
+	for phase := range m.phases {
+		metrics.Phases[phase] = time.Duration(m.phases[phase].Swap(0))
+	}
+	for counter := range m.counters {
+		metrics.Counters[counter] = m.counters[counter].Swap(0)
+	}
+	return metrics
+}
+
+func (w *World) EnableMetrics(enabled bool) {
+	if !enabled {
+		w.metrics = nil
+		return
+	}
+	if w.metrics == nil {
+		w.metrics = &metricsRecorder{}
+	}
+}
+
+func (w *World) MetricsEnabled() bool {
+	return w.metrics != nil
+}
+
+func (w *World) LastTurnMetrics() TurnMetrics {
+	return w.lastMetrics
+}
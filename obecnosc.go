@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type occupancyFrame struct {
+	turn  int
+	cells map[string][][2]int
+}
+
+type Occupancy struct {
+	Window  int
+	Horizon int
+	width   int
+	height  int
+	counts  map[string]*SparseGrid[int]
+	frames  []occupancyFrame
+	first   int
+	dropped int
+}
+
+func NewOccupancy(window int) *Occupancy {
+	return &Occupancy{Window: window, counts: make(map[string]*SparseGrid[int])}
+}
+
+func (o *Occupancy) Record(w *World) {
+	if o == nil {
+		return
+	}
+	o.width, o.height = w.Width, w.Height
+	o.truncate(w.Turn)
+
+	frame := occupancyFrame{turn: w.Turn, cells: make(map[string][][2]int)}
+	w.ForEachOrganism(func(organism Organism) {
+		x, y := organism.GetPosition()
+		frame.cells[organism.GetType()] = append(frame.cells[organism.GetType()], [2]int{x, y})
+	})
+	o.frames = append(o.frames, frame)
+	o.apply(frame, 1)
+	o.slide()
+	o.trim()
+}
+
+func (o *Occupancy) truncate(turn int) {
+	for len(o.frames) > 0 && o.frames[len(o.frames)-1].turn >= turn {
+		if len(o.frames) > o.first {
+			o.apply(o.frames[len(o.frames)-1], -1)
+		}
+		o.frames = o.frames[:len(o.frames)-1]
+	}
+	o.first = min(o.first, len(o.frames))
+}
+
+func (o *Occupancy) trim() {
+	drop := o.first - o.Horizon
+	if o.Window <= 0 {
+		drop = len(o.frames) - o.Horizon
+		o.dropped += max(0, drop)
+	}
+	if drop > 0 {
+		o.frames = o.frames[drop:]
+		o.first = max(0, o.first-drop)
+	}
+}
+
+func (o *Occupancy) Until(turn int) *Occupancy {
+	if o == nil || len(o.frames) == 0 || o.frames[len(o.frames)-1].turn <= turn {
+		return o
+	}
+	view := *o
+	view.counts = make(map[string]*SparseGrid[int], len(o.counts))
+	for organismType, grid := range o.counts {
+		view.counts[organismType] = grid.Clone()
+	}
+	view.frames = o.frames[:len(o.frames):len(o.frames)]
+	view.truncate(turn + 1)
+	view.slide()
+	return &view
+}
+
+func (o *Occupancy) slide() {
+	if o.Window > 0 && o.dropped > 0 {
+		clear(o.counts)
+		o.first, o.dropped = len(o.frames), 0
+	}
+	if o.Window <= 0 {
+		for o.first > 0 {
+			o.first--
+			o.apply(o.frames[o.first], 1)
+		}
+		return
+	}
+	for o.first > 0 && len(o.frames)-o.first < o.Window {
+		o.first--
+		o.apply(o.frames[o.first], 1)
+	}
+	for len(o.frames)-o.first > o.Window {
+		o.apply(o.frames[o.first], -1)
+		o.first++
+	}
+}
+
+func (o *Occupancy) apply(frame occupancyFrame, delta int) {
+	for organismType, cells := range frame.cells {
+		grid := o.counts[organismType]
+		if grid == nil {
+			grid = NewSparseGrid[int](o.width)
+			o.counts[organismType] = grid
+		}
+		for _, cell := range cells {
+			grid.Set(cell[0], cell[1], grid.Get(cell[0], cell[1])+delta)
+		}
+	}
+}
+
+func (o *Occupancy) Turns() int {
+	if o == nil {
+		return 0
+	}
+	return len(o.frames) - o.first + o.dropped
+}
+
+func (o *Occupancy) Count(organismType string, x, y int) int {
+	if o == nil || o.counts[organismType] == nil {
+		return 0
+	}
+	return o.counts[organismType].Get(x, y)
+}
+
+func (o *Occupancy) Max(organismType string) int {
+	top := 0
+	if o == nil || o.counts[organismType] == nil {
+		return top
+	}
+	for _, count := range o.counts[organismType].cells {
+		top = max(top, count)
+	}
+	return top
+}
+
+func (o *Occupancy) WriteCSV(out io.Writer) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"species", "x", "y", "count", "share"}); err != nil {
+		return err
+	}
+	turns := max(1, o.Turns())
+	for _, organismType := range placementOrder {
+		grid := o.counts[organismType]
+		if grid == nil {
+			continue
+		}
+		var err error
+		grid.Each(func(x, y, count int) {
+			if err == nil {
+				err = writer.Write([]string{
+					organismType,
+					strconv.Itoa(x),
+					strconv.Itoa(y),
+					strconv.Itoa(count),
+					strconv.FormatFloat(float64(count)/float64(turns), 'f', 4, 64),
+				})
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (o *Occupancy) Image(species []string, cellSize int) image.Image {
+	const gap = 4
+	scale := fitScale(max(1, cellSize), maxImageSize-(gap+1)*len(species), len(species)*o.width, o.height)
+	panel := int(math.Ceil(float64(o.width) * scale))
+	height := int(math.Ceil(float64(o.height) * scale))
+	img := image.NewRGBA(image.Rect(0, 0, len(species)*(panel+gap)-gap, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	for i, organismType := range species {
+		offset := image.Pt(i*(panel+gap), 0)
+		background := DefaultColors["Open"]
+		draw.Draw(img, image.Rect(offset.X, 0, offset.X+panel, height), &image.Uniform{background}, image.Point{}, draw.Src)
+		top := o.Max(organismType)
+		if top == 0 {
+			continue
+		}
+		pixels := make(map[image.Rectangle]int)
+		o.counts[organismType].Each(func(x, y, count int) {
+			rect := cellRect(x, y, scale).Add(offset)
+			pixels[rect] = max(pixels[rect], count)
+		})
+		for rect, count := range pixels {
+			c := shade(DefaultColors[organismType], background, 0.15+0.85*float64(count)/float64(top))
+			draw.Draw(img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+func (o *Occupancy) Export(out io.Writer, format string, species []string) error {
+	if len(species) == 0 {
+		species = placementOrder
+	}
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "csv":
+		return o.WriteCSV(out)
+	case "png":
+		return png.Encode(out, o.Image(species, 8))
+	}
+	return fmt.Errorf("nieznany format mapy cieplnej %q", format)
+}
@@ -0,0 +1,114 @@
+package main
+
+type MemoryKind int
+
+const (
+	FoodMemory MemoryKind = iota
+	DangerMemory
+)
+
+type MemoryEntry struct {
+	X, Y     int
+	Kind     MemoryKind
+	Strength float64
+	Turn     int
+}
+
+type Memory struct {
+	entries  []MemoryEntry
+	capacity int
+	decay    float64
+}
+
+func NewMemory(capacity int, decay float64) *Memory {
+	return &Memory{
+		capacity: capacity,
+		decay:    decay,
+	}
+}
+
+func (m *Memory) Remember(x, y int, kind MemoryKind, turn int) {
+	if m == nil || m.capacity <= 0 {
+		return
+	}
+	for i := range m.entries {
+		if m.entries[i].X == x && m.entries[i].Y == y && m.entries[i].Kind == kind {
+			m.entries[i].Strength = 1
+			m.entries[i].Turn = turn
+			return
+		}
+	}
+	entry := MemoryEntry{X: x, Y: y, Kind: kind, Strength: 1, Turn: turn}
+	if len(m.entries) < m.capacity {
+		m.entries = append(m.entries, entry)
+		return
+	}
+	weakest := 0
+	for i := range m.entries {
+		if m.entries[i].Strength < m.entries[weakest].Strength {
+			weakest = i
+		}
+	}
+	m.entries[weakest] = entry
+}
+
+func (m *Memory) Forget(x, y int, kind MemoryKind) {
+	if m == nil {
+		return
+	}
+	for i := range m.entries {
+		if m.entries[i].X == x && m.entries[i].Y == y && m.entries[i].Kind == kind {
+			m.entries = append(m.entries[:i], m.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *Memory) Decay() {
+	if m == nil {
+		return
+	}
+	kept := m.entries[:0]
+	for _, entry := range m.entries {
+		entry.Strength *= m.decay
+		if entry.Strength >= 0.1 {
+			kept = append(kept, entry)
+		}
+	}
+	m.entries = kept
+}
+
+func (m *Memory) Strongest(kind MemoryKind) (MemoryEntry, bool) {
+	var best MemoryEntry
+	found := false
+	if m == nil {
+		return best, false
+	}
+	for _, entry := range m.entries {
+		if entry.Kind == kind && (!found || entry.Strength > best.Strength) {
+			best = entry
+			found = true
+		}
+	}
+	return best, found
+}
+
+func (m *Memory) DangerAt(x, y int) float64 {
+	danger := 0.0
+	if m == nil {
+		return danger
+	}
+	for _, entry := range m.entries {
+		if entry.Kind == DangerMemory {
+			danger += entry.Strength / float64(1+squaredDistance(x, y, entry.X, entry.Y))
+		}
+	}
+	return danger
+}
+
+func (m *Memory) Entries() []MemoryEntry {
+	if m == nil {
+		return nil
+	}
+	return m.entries
+}
@@ -0,0 +1,122 @@
+package main
+
+func (w *World) CanSee(observer, target Organism) bool {
+	ox, oy := observer.GetPosition()
+	tx, ty := target.GetPosition()
+	traits := observer.GetTraits()
+	distance := squaredDistance(ox, oy, tx, ty)
+	if distance > traits.VisionRadius*traits.VisionRadius {
+		return false
+	}
+	if w.GetTerrain(tx, ty) == Cover && max(abs(tx-ox), abs(ty-oy)) > traits.CoverDetection {
+		return false
+	}
+	return w.HasLineOfSight(ox, oy, tx, ty)
+}
+
+func (w *World) HasLineOfSight(x0, y0, x1, y1 int) bool {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	x, y := x0, y0
+	for {
+		if x == x1 && y == y1 {
+			return true
+		}
+		if (x != x0 || y != y0) && w.GetTerrain(x, y).BlocksSight() {
+			return false
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+func (w *World) Perceive(observer Organism) []Organism {
+	w.metrics.count(PerceptionQueries)
+	var seen []Organism
+	x, y := observer.GetPosition()
+	w.index.InRadius(x, y, observer.GetTraits().VisionRadius, func(target Organism) {
+		if target != observer && target.GetEnergy() > 0 && w.CanSee(observer, target) {
+			seen = append(seen, target)
+		}
+	})
+	return seen
+}
+
+func (w *World) FindVisible(observer Organism, match func(Organism) bool) []Organism {
+	var found []Organism
+	for _, target := range w.Perceive(observer) {
+		if match(target) {
+			found = append(found, target)
+		}
+	}
+	return found
+}
+
+func (w *World) FindVisibleFood(observer Organism) []Organism {
+	return w.FindVisible(observer, func(target Organism) bool {
+		return eats(observer, target)
+	})
+}
+
+func (w *World) FindVisiblePartners(observer Organism) []Organism {
+	w.metrics.count(PartnerSearches)
+	return w.FindVisible(observer, func(target Organism) bool {
+		return target.GetType() == observer.GetType() &&
+			target.CanBreed() &&
+			!target.HasBred()
+	})
+}
+
+func (w *World) FindVisiblePredators(observer Organism) []Organism {
+	return w.FindVisible(observer, func(target Organism) bool {
+		return eats(target, observer)
+	})
+}
+
+func nearest(organism Organism, candidates []Organism) Organism {
+	x, y := organism.GetPosition()
+	var closest Organism
+	closestDistance := -1
+	for _, candidate := range candidates {
+		cx, cy := candidate.GetPosition()
+		if distance := squaredDistance(x, y, cx, cy); closestDistance < 0 || distance < closestDistance {
+			closest = candidate
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+func eats(eater, food Organism) bool {
+	for _, foodType := range eater.GetDiet() {
+		if food.GetType() == foodType {
+			return true
+		}
+	}
+	return false
+}
+
+func squaredDistance(x0, y0, x1, y1 int) int {
+	return (x1-x0)*(x1-x0) + (y1-y0)*(y1-y0)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+var DefaultColors = map[string]color.RGBA{
+	"Fox":      {0xe0, 0x70, 0x10, 0xff},
+	"Rabbit":   {0x90, 0x90, 0xa0, 0xff},
+	"Grass":    {0x40, 0xb0, 0x40, 0xff},
+	"Open":     {0xf4, 0xf1, 0xe8, 0xff},
+	"Obstacle": {0x50, 0x50, 0x50, 0xff},
+	"Cover":    {0x1f, 0x60, 0x2a, 0xff},
+	"Grid":     {0xd8, 0xd4, 0xc8, 0xff},
+	"Text":     {0x00, 0x00, 0x00, 0xff},
+}
+
+const (
+	maxImageSize     = 4096
+	maxAnimationSize = 1024
+)
+
+var colorNames = []string{"Open", "Obstacle", "Cover", "Fox", "Rabbit", "Grass", "Grid", "Text"}
+
+type Renderer struct {
+	CellSize      int
+	MaxSize       int
+	Colors        map[string]color.RGBA
+	GridLines     bool
+	EnergyShading bool
+	ShowTurn      bool
+	Sprites       map[string]image.Image
+}
+
+func DefaultRenderer() Renderer {
+	return Renderer{CellSize: 8, MaxSize: maxImageSize, Colors: DefaultColors, ShowTurn: true}
+}
+
+func ParseColors(spec string) (map[string]color.RGBA, error) {
+	colors := make(map[string]color.RGBA, len(DefaultColors))
+	for name, c := range DefaultColors {
+		colors[name] = c
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if _, known := DefaultColors[name]; !ok || !known {
+			return nil, fmt.Errorf("kolor %q: oczekiwano <nazwa>=#rrggbb", part)
+		}
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(value, "#%02x%02x%02x", &r, &g, &b); err != nil {
+			return nil, fmt.Errorf("kolor %q: niepoprawna wartość %q", part, value)
+		}
+		colors[name] = color.RGBA{r, g, b, 0xff}
+	}
+	return colors, nil
+}
+
+func LoadSprites(dir string) (map[string]image.Image, error) {
+	sprites := make(map[string]image.Image)
+	for _, organismType := range placementOrder {
+		file, err := os.Open(filepath.Join(dir, organismType+".png"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sprite, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s.png: %v", organismType, err)
+		}
+		sprites[organismType] = sprite
+	}
+	return sprites, nil
+}
+
+func (r Renderer) Render(w *World) image.Image {
+	img := image.NewRGBA(r.bounds(w))
+	r.Draw(img, w)
+	return img
+}
+
+func (r Renderer) bounds(w *World) image.Rectangle {
+	scale := r.scale(w)
+	return image.Rect(0, 0, int(math.Ceil(float64(w.Width)*scale)), int(math.Ceil(float64(w.Height)*scale)))
+}
+
+func (r Renderer) scale(w *World) float64 {
+	limit := r.MaxSize
+	if limit <= 0 {
+		limit = maxImageSize
+	}
+	return fitScale(max(1, r.CellSize), limit, w.Width, w.Height)
+}
+
+func fitScale(cellSize, limit, width, height int) float64 {
+	return min(float64(cellSize), float64(limit)/float64(max(1, width, height)))
+}
+
+func cellRect(x, y int, scale float64) image.Rectangle {
+	x0, y0 := int(float64(x)*scale), int(float64(y)*scale)
+	return image.Rect(x0, y0, max(x0+1, int(float64(x+1)*scale)), max(y0+1, int(float64(y+1)*scale)))
+}
+
+func (r Renderer) color(name string) color.RGBA {
+	if c, ok := r.Colors[name]; ok {
+		return c
+	}
+	return DefaultColors[name]
+}
+
+func (r Renderer) Draw(dst draw.Image, w *World) {
+	scale := r.scale(w)
+	fill := func(x, y int, c color.Color) {
+		draw.Draw(dst, cellRect(x, y, scale), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{r.color("Open")}, image.Point{}, draw.Src)
+	w.terrain.Each(func(x, y int, terrain Terrain) {
+		switch terrain {
+		case Obstacle:
+			fill(x, y, r.color("Obstacle"))
+		case Cover:
+			fill(x, y, r.color("Cover"))
+		}
+	})
+
+	maxEnergy := make(map[string]int)
+	if r.EnergyShading {
+		w.ForEachOrganism(func(organism Organism) {
+			maxEnergy[organism.GetType()] = max(maxEnergy[organism.GetType()], organism.GetEnergy())
+		})
+	}
+	w.ForEachOrganism(func(organism Organism) {
+		x, y := organism.GetPosition()
+		if sprite, ok := r.Sprites[organism.GetType()]; ok {
+			xdraw.NearestNeighbor.Scale(dst, cellRect(x, y, scale), sprite, sprite.Bounds(), draw.Over, nil)
+			return
+		}
+		c := r.color(organism.GetType())
+		if top := maxEnergy[organism.GetType()]; top > 0 {
+			c = shade(c, r.color("Open"), 0.3+0.7*float64(organism.GetEnergy())/float64(top))
+		}
+		fill(x, y, c)
+	})
+
+	if r.GridLines && scale >= 3 {
+		line := &image.Uniform{r.color("Grid")}
+		bounds := dst.Bounds()
+		for x := 0; x <= w.Width; x++ {
+			left := int(float64(x) * scale)
+			draw.Draw(dst, image.Rect(left, 0, left+1, bounds.Max.Y), line, image.Point{}, draw.Src)
+		}
+		for y := 0; y <= w.Height; y++ {
+			top := int(float64(y) * scale)
+			draw.Draw(dst, image.Rect(0, top, bounds.Max.X, top+1), line, image.Point{}, draw.Src)
+		}
+	}
+	if r.ShowTurn {
+		drawer := font.Drawer{
+			Dst:  dst,
+			Src:  &image.Uniform{r.color("Text")},
+			Face: basicfont.Face7x13,
+			Dot:  fixed.P(3, 12),
+		}
+		drawer.DrawString(fmt.Sprintf("Tura %d", w.Turn))
+	}
+}
+
+func shade(c, background color.RGBA, strength float64) color.RGBA {
+	strength = min(1, max(0, strength))
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*strength + float64(b)*(1-strength))
+	}
+	return color.RGBA{mix(c.R, background.R), mix(c.G, background.G), mix(c.B, background.B), 0xff}
+}
+
+func SaveFrame(r Renderer, w *World, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, r.Render(w)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
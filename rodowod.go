@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var lineageSpecies = []string{"Fox", "Rabbit"}
+
+type LineageNode struct {
+	ID        int
+	Type      string
+	Parents   []int
+	BirthTurn int
+	DeathTurn int
+	Founders  []int
+	firstSeen int
+}
+
+func (n *LineageNode) Alive() bool {
+	return n.DeathTurn < 0
+}
+
+type FounderCount struct {
+	Turn   int
+	Counts map[string]int
+}
+
+type Lineage struct {
+	Nodes     map[int]*LineageNode
+	Survivors []FounderCount
+	alive     map[int]*LineageNode
+}
+
+func NewLineage() *Lineage {
+	return &Lineage{Nodes: make(map[int]*LineageNode), alive: make(map[int]*LineageNode)}
+}
+
+func (l *Lineage) Record(w *World) {
+	if l == nil {
+		return
+	}
+	if len(l.Survivors) > 0 && l.Survivors[len(l.Survivors)-1].Turn >= w.Turn {
+		l.rewind(w.Turn)
+	}
+
+	seen := make(map[int]bool, len(l.alive))
+	for _, organismType := range lineageSpecies {
+		for _, organism := range w.index.ByType(organismType) {
+			id := organism.GetID()
+			seen[id] = true
+			if _, known := l.Nodes[id]; known {
+				continue
+			}
+			node := &LineageNode{
+				ID:        id,
+				Type:      organismType,
+				Parents:   append([]int(nil), organism.GetParents()...),
+				BirthTurn: organism.GetBirthTurn(),
+				DeathTurn: -1,
+				firstSeen: w.Turn,
+			}
+			node.Founders = l.founders(node)
+			l.Nodes[id] = node
+			l.alive[id] = node
+		}
+	}
+	for id, node := range l.alive {
+		if !seen[id] {
+			node.DeathTurn = w.Turn
+			delete(l.alive, id)
+		}
+	}
+
+	founders := make(map[string]map[int]bool)
+	for _, node := range l.alive {
+		if founders[node.Type] == nil {
+			founders[node.Type] = make(map[int]bool)
+		}
+		for _, founder := range node.Founders {
+			founders[node.Type][founder] = true
+		}
+	}
+	count := FounderCount{Turn: w.Turn, Counts: make(map[string]int)}
+	for _, organismType := range lineageSpecies {
+		count.Counts[organismType] = len(founders[organismType])
+	}
+	l.Survivors = append(l.Survivors, count)
+}
+
+func (l *Lineage) founders(node *LineageNode) []int {
+	set := make(map[int]bool)
+	for _, id := range node.Parents {
+		if parent, ok := l.Nodes[id]; ok {
+			for _, founder := range parent.Founders {
+				set[founder] = true
+			}
+		}
+	}
+	if len(set) == 0 {
+		return []int{node.ID}
+	}
+	founders := make([]int, 0, len(set))
+	for founder := range set {
+		founders = append(founders, founder)
+	}
+	sort.Ints(founders)
+	return founders
+}
+
+func (l *Lineage) rewind(turn int) {
+	for len(l.Survivors) > 0 && l.Survivors[len(l.Survivors)-1].Turn >= turn {
+		l.Survivors = l.Survivors[:len(l.Survivors)-1]
+	}
+	for id, node := range l.Nodes {
+		if node.firstSeen >= turn {
+			delete(l.Nodes, id)
+			delete(l.alive, id)
+		} else if node.DeathTurn >= turn {
+			node.DeathTurn = -1
+			l.alive[id] = node
+		}
+	}
+}
+
+func (l *Lineage) Until(turn int) *Lineage {
+	if l == nil || len(l.Survivors) == 0 || l.Survivors[len(l.Survivors)-1].Turn <= turn {
+		return l
+	}
+	view := &Lineage{
+		Nodes:     make(map[int]*LineageNode, len(l.Nodes)),
+		Survivors: l.Survivors[:len(l.Survivors):len(l.Survivors)],
+		alive:     make(map[int]*LineageNode),
+	}
+	for id, node := range l.Nodes {
+		copied := *node
+		view.Nodes[id] = &copied
+		if copied.Alive() {
+			view.alive[id] = &copied
+		}
+	}
+	view.rewind(turn + 1)
+	return view
+}
+
+func (l *Lineage) sortedNodes() []*LineageNode {
+	nodes := make([]*LineageNode, 0, len(l.Nodes))
+	for _, node := range l.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+func (l *Lineage) Descendants(founder int) (total, living int) {
+	for _, node := range l.Nodes {
+		if node.ID != founder && slices.Contains(node.Founders, founder) {
+			total++
+			if node.Alive() {
+				living++
+			}
+		}
+	}
+	return total, living
+}
+
+func (l *Lineage) WriteDOT(out io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph rodowod {\n\trankdir=LR;\n\tnode [shape=box, style=filled];\n")
+	for _, node := range l.sortedNodes() {
+		fill := "#f4a261"
+		if node.Type == "Rabbit" {
+			fill = "#c8c8d4"
+		}
+		if !node.Alive() {
+			fill = "#eeeeee"
+		}
+		label := fmt.Sprintf("%s #%d\\nur. %d", node.Type, node.ID, node.BirthTurn)
+		if !node.Alive() {
+			label += fmt.Sprintf(", zm. %d", node.DeathTurn)
+		}
+		fmt.Fprintf(&b, "\tn%d [label=\"%s\", fillcolor=\"%s\"];\n", node.ID, label, fill)
+		for i, parent := range node.Parents {
+			if _, ok := l.Nodes[parent]; !ok {
+				continue
+			}
+			style := ""
+			if i > 0 {
+				style = " [style=dashed]"
+			}
+			fmt.Fprintf(&b, "\tn%d -> n%d%s;\n", parent, node.ID, style)
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func (l *Lineage) WriteNewick(out io.Writer, organismType string) error {
+	children := make(map[int][]*LineageNode)
+	var roots []*LineageNode
+	for _, node := range l.sortedNodes() {
+		if node.Type != organismType {
+			continue
+		}
+		if len(node.Parents) > 0 {
+			if _, ok := l.Nodes[node.Parents[0]]; ok {
+				children[node.Parents[0]] = append(children[node.Parents[0]], node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	var b strings.Builder
+	var write func(node *LineageNode, parentBirth int)
+	write = func(node *LineageNode, parentBirth int) {
+		if kids := children[node.ID]; len(kids) > 0 {
+			b.WriteString("(")
+			for i, child := range kids {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				write(child, node.BirthTurn)
+			}
+			b.WriteString(")")
+		}
+		fmt.Fprintf(&b, "%s%d:%d", strings.ToLower(organismType[:1]), node.ID, max(0, node.BirthTurn-parentBirth))
+	}
+	b.WriteString("(")
+	for i, root := range roots {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		write(root, root.BirthTurn)
+	}
+	b.WriteString(");\n")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func (l *Lineage) WriteSummary(out io.Writer) error {
+	fmt.Fprintln(out, "turn\tfox_lineages\trabbit_lineages")
+	for _, count := range l.Survivors {
+		if _, err := fmt.Fprintf(out, "%d\t%d\t%d\n", count.Turn, count.Counts["Fox"], count.Counts["Rabbit"]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Lineage) Export(out io.Writer, format string) error {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "dot", "gv":
+		return l.WriteDOT(out)
+	case "nwk", "newick":
+		for _, organismType := range lineageSpecies {
+			if err := l.WriteNewick(out, organismType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "tsv":
+		return l.WriteSummary(out)
+	}
+	return fmt.Errorf("nieznany format rodowodu %q", format)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type PlacementStrategy int
+
+const (
+	UniformPlacement PlacementStrategy = iota
+	ClusteredPlacement
+	PoissonDiscPlacement
+	QuadrantPlacement
+	GradientPlacement
+)
+
+var PlacementNames = []string{"Równomierne", "Skupiska", "Dysk Poissona", "Ćwiartki", "Gradient"}
+var PlacementKeys = []string{"uniform", "clustered", "poisson", "quadrants", "gradient"}
+
+func (s PlacementStrategy) String() string {
+	if int(s) < len(PlacementNames) {
+		return PlacementNames[s]
+	}
+	return "?"
+}
+
+func ParsePlacement(key string) (PlacementStrategy, error) {
+	for i, candidate := range PlacementKeys {
+		if candidate == key {
+			return PlacementStrategy(i), nil
+		}
+	}
+	return UniformPlacement, fmt.Errorf("nieznane rozmieszczenie %q (dostępne: %s)", key, strings.Join(PlacementKeys, ", "))
+}
+
+var placementOrder = []string{"Fox", "Rabbit", "Grass"}
+
+type PlacementReport struct {
+	Strategy  PlacementStrategy
+	Requested map[string]int
+	Placed    map[string]int
+}
+
+func (r PlacementReport) Complete() bool {
+	for organismType, requested := range r.Requested {
+		if r.Placed[organismType] < requested {
+			return false
+		}
+	}
+	return true
+}
+
+func (r PlacementReport) String() string {
+	parts := make([]string, 0, len(placementOrder))
+	for _, organismType := range placementOrder {
+		if requested, ok := r.Requested[organismType]; ok {
+			parts = append(parts, fmt.Sprintf("%s %d/%d", organismType, r.Placed[organismType], requested))
+		}
+	}
+	return fmt.Sprintf("rozmieszczenie %s: %s", r.Strategy, strings.Join(parts, ", "))
+}
+
+type placer struct {
+	world    *World
+	strategy PlacementStrategy
+	centres  map[string][][2]float64
+	spread   float64
+	spacing  float64
+}
+
+func (w *World) Populate(strategy PlacementStrategy, counts map[string]int) PlacementReport {
+	report := PlacementReport{
+		Strategy:  strategy,
+		Requested: make(map[string]int),
+		Placed:    make(map[string]int),
+	}
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	p := &placer{
+		world:    w,
+		strategy: strategy,
+		centres:  make(map[string][][2]float64),
+		spread:   math.Max(2, float64(min(w.Width, w.Height))/10),
+		spacing:  0.9 * math.Sqrt(float64(w.Width*w.Height)/(math.Pi*float64(max(1, total)))),
+	}
+
+	for _, organismType := range placementOrder {
+		count, ok := counts[organismType]
+		if !ok {
+			continue
+		}
+		report.Requested[organismType] = count
+		for i := 0; i < count; i++ {
+			for attempts := 0; attempts < 100; attempts++ {
+				x, y := p.candidate(organismType, count)
+				if w.IsEmpty(x, y) && p.accepts(x, y) {
+					w.PlaceOrganism(NewOrganism(organismType, w.nextID, x, y))
+					w.nextID++
+					report.Placed[organismType]++
+					break
+				}
+			}
+		}
+	}
+	w.Log(report.String())
+	return report
+}
+
+func (p *placer) candidate(organismType string, count int) (int, int) {
+	w := p.world
+	switch p.strategy {
+	case ClusteredPlacement:
+		centres, ok := p.centres[organismType]
+		if !ok {
+			for i := 0; i < max(1, count/10); i++ {
+				centres = append(centres, [2]float64{w.rng.Float64() * float64(w.Width), w.rng.Float64() * float64(w.Height)})
+			}
+			p.centres[organismType] = centres
+		}
+		centre := centres[w.rng.Intn(len(centres))]
+		dx, dy := gaussianPair(w.rng)
+		return int(math.Floor(centre[0] + dx*p.spread)), int(math.Floor(centre[1] + dy*p.spread))
+	case QuadrantPlacement:
+		halfW, halfH := max(1, w.Width/2), max(1, w.Height/2)
+		x, y := w.rng.Intn(halfW), w.rng.Intn(halfH)
+		switch organismType {
+		case "Rabbit":
+			return w.Width - 1 - x, w.Height - 1 - y
+		case "Grass":
+			if w.rng.Intn(2) == 0 {
+				return w.Width - 1 - x, y
+			}
+			return x, w.Height - 1 - y
+		}
+		return x, y
+	case GradientPlacement:
+		x := int(float64(w.Width) * math.Sqrt(w.rng.Float64()))
+		return min(x, w.Width-1), w.rng.Intn(w.Height)
+	}
+	return w.rng.Intn(w.Width), w.rng.Intn(w.Height)
+}
+
+func (p *placer) accepts(x, y int) bool {
+	if p.strategy != PoissonDiscPlacement || p.spacing < 1 {
+		return true
+	}
+	free := true
+	p.world.index.InRadius(x, y, int(math.Ceil(p.spacing)), func(other Organism) {
+		ox, oy := other.GetPosition()
+		if float64(squaredDistance(x, y, ox, oy)) < p.spacing*p.spacing {
+			free = false
+		}
+	})
+	return free
+}
+
+func gaussianPair(rng randSource) (float64, float64) {
+	u := 1 - rng.Float64()
+	v := rng.Float64()
+	r := math.Sqrt(-2 * math.Log(u))
+	return r * math.Cos(2*math.Pi*v), r * math.Sin(2*math.Pi*v)
+}
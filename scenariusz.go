@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type Region struct {
+	MinX, MinY, MaxX, MaxY int
+	Name                   string
+}
+
+func (r Region) resolve(w *World) (int, int, int, int) {
+	switch r.Name {
+	case "north":
+		return 0, 0, w.Width - 1, w.Height/2 - 1
+	case "south":
+		return 0, w.Height / 2, w.Width - 1, w.Height - 1
+	case "west":
+		return 0, 0, w.Width/2 - 1, w.Height - 1
+	case "east":
+		return w.Width / 2, 0, w.Width - 1, w.Height - 1
+	case "all":
+		return 0, 0, w.Width - 1, w.Height - 1
+	}
+	return max(0, r.MinX), max(0, r.MinY), min(w.Width-1, r.MaxX), min(w.Height-1, r.MaxY)
+}
+
+func (r Region) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%d,%d-%d,%d", r.MinX, r.MinY, r.MaxX, r.MaxY)
+}
+
+type ScenarioAction struct {
+	Kind    string
+	Species string
+	Count   int
+	Value   float64
+	Region  Region
+}
+
+type ScenarioEvent struct {
+	Turn   int
+	Every  int
+	Until  int
+	Action ScenarioAction
+	Line   int
+}
+
+func (e ScenarioEvent) firesAt(turn int) bool {
+	if e.Every <= 0 {
+		return turn == e.Turn
+	}
+	return turn >= e.Turn && (e.Until <= 0 || turn <= e.Until) && (turn-e.Turn)%e.Every == 0
+}
+
+type Scenario struct {
+	Events []ScenarioEvent
+}
+
+type LogEntry struct {
+	Turn    int
+	Message string
+}
+
+func LoadScenario(path string) (*Scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseScenario(file)
+}
+
+func ParseScenario(r io.Reader) (*Scenario, error) {
+	scenario := &Scenario{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		event, err := parseScenarioLine(strings.Fields(text))
+		if err != nil {
+			return nil, fmt.Errorf("linia %d: %w", line, err)
+		}
+		event.Line = line
+		scenario.Events = append(scenario.Events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return scenario, nil
+}
+
+func parseScenarioLine(fields []string) (ScenarioEvent, error) {
+	var event ScenarioEvent
+	if len(fields) < 3 {
+		return event, fmt.Errorf("za mało pól")
+	}
+	number, err := strconv.Atoi(fields[1])
+	if err != nil || number < 0 {
+		return event, fmt.Errorf("niepoprawna tura %q", fields[1])
+	}
+	switch fields[0] {
+	case "at", "from":
+		event.Turn = number
+	case "every":
+		event.Every = number
+		event.Turn = number
+		if number == 0 {
+			return event, fmt.Errorf("okres musi być dodatni")
+		}
+	default:
+		return event, fmt.Errorf("nieznany wyzwalacz %q", fields[0])
+	}
+	fields = fields[2:]
+	for event.Every > 0 && len(fields) >= 2 && (fields[0] == "from" || fields[0] == "until") {
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return event, fmt.Errorf("niepoprawna tura %q", fields[1])
+		}
+		if fields[0] == "from" {
+			event.Turn = value
+		} else {
+			event.Until = value
+		}
+		fields = fields[2:]
+	}
+	if len(fields) == 0 {
+		return event, fmt.Errorf("brak akcji")
+	}
+	action, err := parseScenarioAction(fields)
+	event.Action = action
+	return event, err
+}
+
+func parseScenarioAction(fields []string) (ScenarioAction, error) {
+	action := ScenarioAction{Kind: fields[0], Region: Region{Name: "all"}}
+	args := fields[1:]
+	if n := len(args); n >= 2 && args[n-2] == "in" {
+		region, err := ParseRegion(args[n-1])
+		if err != nil {
+			return action, err
+		}
+		action.Region = region
+		args = args[:n-2]
+	}
+
+	switch action.Kind {
+	case "add":
+		if len(args) != 2 {
+			return action, fmt.Errorf("użycie: add <gatunek> <liczba> [in <obszar>]")
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count < 0 {
+			return action, fmt.Errorf("niepoprawna liczba %q", args[1])
+		}
+		action.Species, action.Count = args[0], count
+	case "remove":
+		if len(args) != 1 {
+			return action, fmt.Errorf("użycie: remove <gatunek|all> [in <obszar>]")
+		}
+		action.Species = args[0]
+	case "grass-rate", "grass-interval":
+		if len(args) != 1 {
+			return action, fmt.Errorf("użycie: %s <wartość>", action.Kind)
+		}
+		value, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || value < 0 {
+			return action, fmt.Errorf("niepoprawna wartość %q", args[0])
+		}
+		action.Value = value
+		return action, nil
+	default:
+		return action, fmt.Errorf("nieznana akcja %q", action.Kind)
+	}
+	if (action.Kind != "remove" || action.Species != "all") && NewOrganism(action.Species, 0, 0, 0) == nil {
+		return action, fmt.Errorf("nieznany gatunek %q", action.Species)
+	}
+	return action, nil
+}
+
+func ParseRegion(text string) (Region, error) {
+	switch text {
+	case "all", "north", "south", "east", "west":
+		return Region{Name: text}, nil
+	}
+	var region Region
+	if _, err := fmt.Sscanf(text, "%d,%d-%d,%d", &region.MinX, &region.MinY, &region.MaxX, &region.MaxY); err != nil {
+		return region, fmt.Errorf("niepoprawny obszar %q", text)
+	}
+	if region.MinX > region.MaxX {
+		region.MinX, region.MaxX = region.MaxX, region.MinX
+	}
+	if region.MinY > region.MaxY {
+		region.MinY, region.MaxY = region.MaxY, region.MinY
+	}
+	return region, nil
+}
+
+func (w *World) applyScenario() {
+	if w.Scenario == nil {
+		return
+	}
+	for _, event := range w.Scenario.Events {
+		if event.firesAt(w.Turn) {
+			w.Log(w.applyScenarioAction(event.Action))
+		}
+	}
+}
+
+func (w *World) applyScenarioAction(action ScenarioAction) string {
+	minX, minY, maxX, maxY := action.Region.resolve(w)
+	switch action.Kind {
+	case "add":
+		placed := 0
+		if NewOrganism(action.Species, 0, 0, 0) == nil {
+			return fmt.Sprintf("nieznany gatunek %q", action.Species)
+		}
+		for i := 0; i < action.Count && maxX >= minX && maxY >= minY; i++ {
+			for attempts := 0; attempts < 100; attempts++ {
+				x := minX + w.rng.Intn(maxX-minX+1)
+				y := minY + w.rng.Intn(maxY-minY+1)
+				if w.IsEmpty(x, y) {
+					organism := NewOrganism(action.Species, w.nextID, x, y)
+					organism.SetLineage(nil, w.Turn)
+					w.PlaceOrganism(organism)
+					w.arrivals[action.Species]++
+					w.nextID++
+					placed++
+					break
+				}
+			}
+		}
+		return fmt.Sprintf("dodano %d/%d %s w %s", placed, action.Count, action.Species, action.Region)
+	case "remove":
+		var removed []Organism
+		for _, organism := range w.index.All() {
+			x, y := organism.GetPosition()
+			if (action.Species == "all" || organism.GetType() == action.Species) &&
+				x >= minX && x <= maxX && y >= minY && y <= maxY {
+				removed = append(removed, organism)
+			}
+		}
+		for _, organism := range removed {
+			w.kill(organism, RemovedDeath)
+		}
+		return fmt.Sprintf("usunięto %d %s w %s", len(removed), action.Species, action.Region)
+	case "grass-rate":
+		w.GrassSpawnRate = action.Value
+		return fmt.Sprintf("tempo odrastania trawy: %.2f", action.Value)
+	case "grass-interval":
+		w.GrassSpawnInterval = int(action.Value)
+		return fmt.Sprintf("trawa odrasta co %d tur", w.GrassSpawnInterval)
+	}
+	return ""
+}
+
+func (w *World) Log(message string) {
+	w.EventLog = append(w.EventLog, LogEntry{Turn: w.Turn, Message: message})
+}
+
+func (w *World) grassSpawnCount() int {
+	return int(math.Round(float64(w.GrassSpawnCount) * w.GrassSpawnRate))
+}
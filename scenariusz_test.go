@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseScenarioMalformedTriggers(t *testing.T) {
+	for _, line := range []string{
+		"at 10",
+		"at x add Fox 1",
+		"every 0 add Fox 1",
+		"every 50 from 100",
+		"every 50 until 200",
+		"every 50 from 100 until 200",
+		"every 50 from x add Fox 1",
+		"every 50 from",
+		"whenever 5 add Fox 1",
+		"at 10 add Fox",
+		"at 0 add all 3",
+		"at 10 dance",
+	} {
+		if _, err := ParseScenario(strings.NewReader(line)); err == nil {
+			t.Errorf("%q: oczekiwano błędu", line)
+		}
+	}
+}
+
+func TestParseScenarioTriggers(t *testing.T) {
+	scenario, err := ParseScenario(strings.NewReader("every 50 from 100 until 300 add Fox 2 in north"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := scenario.Events[0]
+	if event.Every != 50 || event.Turn != 100 || event.Until != 300 {
+		t.Errorf("wyzwalacz %+v", event)
+	}
+	if event.Action.Kind != "add" || event.Action.Species != "Fox" || event.Action.Count != 2 || event.Action.Region.Name != "north" {
+		t.Errorf("akcja %+v", event.Action)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import "sort"
+
+type SparseGrid[T comparable] struct {
+	width int
+	cells map[int64]T
+}
+
+func NewSparseGrid[T comparable](width int) *SparseGrid[T] {
+	return &SparseGrid[T]{
+		width: width,
+		cells: make(map[int64]T),
+	}
+}
+
+func (g *SparseGrid[T]) key(x, y int) int64 {
+	return int64(y)*int64(g.width) + int64(x)
+}
+
+func (g *SparseGrid[T]) Get(x, y int) T {
+	return g.cells[g.key(x, y)]
+}
+
+func (g *SparseGrid[T]) Set(x, y int, value T) {
+	var zero T
+	if value == zero {
+		delete(g.cells, g.key(x, y))
+		return
+	}
+	g.cells[g.key(x, y)] = value
+}
+
+func (g *SparseGrid[T]) Len() int {
+	return len(g.cells)
+}
+
+func (g *SparseGrid[T]) Clone() *SparseGrid[T] {
+	clone := NewSparseGrid[T](g.width)
+	for key, value := range g.cells {
+		clone.cells[key] = value
+	}
+	return clone
+}
+
+func (g *SparseGrid[T]) Each(fn func(x, y int, value T)) {
+	keys := make([]int64, 0, len(g.cells))
+	for key := range g.cells {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, key := range keys {
+		fn(int(key%int64(g.width)), int(key/int64(g.width)), g.cells[key])
+	}
+}
@@ -1,29 +1,74 @@
 package main
 
 import (
-	"math/rand"
+	"time"
 )
 
 type World struct {
-	Grid   [][]Organism
-	Width  int
-	Height int
-	Turn   int
-	nextID int
+	Width        int
+	Height       int
+	Turn         int
+	Seed         int64
+	UpdateMode   UpdateMode
+	ConflictRule ConflictRule
+	Scenario     *Scenario
+	EventLog     []LogEntry
+	Workers      int
+	TileSize     int
+	History      *History
+	Lineage      *Lineage
+	Trajectories *Trajectories
+	Occupancy    *Occupancy
+
+	GrassSpawnInterval int
+	GrassSpawnCount    int
+	GrassSpawnRate     float64
+
+	nextID      int
+	rng         *splitMix
+	grid        *SparseGrid[Organism]
+	terrain     *SparseGrid[Terrain]
+	index       *OrganismIndex
+	buffer      []Organism
+	metrics     *metricsRecorder
+	lastMetrics TurnMetrics
+	births      map[string]int
+	arrivals    map[string]int
+	deaths      map[string]int
+	causes      map[deathKey]int
+	meals       map[mealKey]int
+	actions     map[int][]ActionKind
 }
 
 func NewWorld(width, height int) *World {
-	grid := make([][]Organism, height)
-	for i := range grid {
-		grid[i] = make([]Organism, width)
-	}
-	return &World{
-		Grid:   grid,
-		Width:  width,
-		Height: height,
-		Turn:   0,
-		nextID: 1,
+	w := &World{
+		Width:    width,
+		Height:   height,
+		Turn:     0,
+		Workers:  1,
+		TileSize: 32,
+
+		GrassSpawnInterval: 5,
+		GrassSpawnCount:    5,
+		GrassSpawnRate:     1,
+
+		nextID:   1,
+		grid:     NewSparseGrid[Organism](width),
+		terrain:  NewSparseGrid[Terrain](width),
+		index:    NewOrganismIndex(),
+		births:   make(map[string]int),
+		arrivals: make(map[string]int),
+		deaths:   make(map[string]int),
+		causes:   make(map[deathKey]int),
+		meals:    make(map[mealKey]int),
 	}
+	w.SetSeed(time.Now().UnixNano())
+	return w
+}
+
+func (w *World) SetSeed(seed int64) {
+	w.Seed = seed
+	w.rng = newSplitMix(seed)
 }
 
 func (w *World) IsValidPosition(x, y int) bool {
@@ -31,14 +76,14 @@ func (w *World) IsValidPosition(x, y int) bool {
 }
 
 func (w *World) IsEmpty(x, y int) bool {
-	return w.IsValidPosition(x, y) && w.Grid[y][x] == nil
+	return w.IsValidPosition(x, y) && w.grid.Get(x, y) == nil && !w.terrain.Get(x, y).BlocksMovement()
 }
 
 func (w *World) GetOrganism(x, y int) Organism {
 	if !w.IsValidPosition(x, y) {
 		return nil
 	}
-	return w.Grid[y][x]
+	return w.grid.Get(x, y)
 }
 
 func (w *World) PlaceOrganism(organism Organism) bool {
@@ -46,14 +91,26 @@ func (w *World) PlaceOrganism(organism Organism) bool {
 	if !w.IsEmpty(x, y) {
 		return false
 	}
-	w.Grid[y][x] = organism
+	w.grid.Set(x, y, organism)
+	w.index.Add(organism)
 	return true
 }
 
 func (w *World) RemoveOrganism(x, y int) {
-	if w.IsValidPosition(x, y) {
-		w.Grid[y][x] = nil
+	if !w.IsValidPosition(x, y) {
+		return
 	}
+	if organism := w.grid.Get(x, y); organism != nil {
+		w.grid.Set(x, y, nil)
+		w.index.Remove(organism)
+		w.deaths[organism.GetType()]++
+	}
+}
+
+func (w *World) kill(organism Organism, cause DeathCause) {
+	w.RemoveOrganism(organism.GetPosition())
+	organism.Die()
+	w.causes[deathKey{organism.GetType(), cause}]++
 }
 
 func (w *World) MoveOrganism(fromX, fromY, toX, toY int) bool {
@@ -61,18 +118,20 @@ func (w *World) MoveOrganism(fromX, fromY, toX, toY int) bool {
 		return false
 	}
 
-	organism := w.Grid[fromY][fromX]
+	organism := w.grid.Get(fromX, fromY)
 	if organism == nil || !organism.CanMove() {
 		return false
 	}
 
-	w.Grid[fromY][fromX] = nil
-	w.Grid[toY][toX] = organism
+	w.grid.Set(fromX, fromY, nil)
+	w.grid.Set(toX, toY, organism)
 	organism.Move(toX, toY)
+	w.index.Move(organism, fromX, fromY)
 	return true
 }
 
 func (w *World) GetEmptyNeighborPositions(x, y int) [][2]int {
+	w.metrics.count(NeighborQueries)
 	var positions [][2]int
 	directions := [][]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
 
@@ -103,130 +162,238 @@ func (w *World) FindFood(x, y int, diet []string) []Organism {
 	return food
 }
 func (w *World) GetStatistics() map[string]int {
-	stats := map[string]int{"Fox": 0, "Rabbit": 0, "Grass": 0}
+	return w.index.Counts()
+}
 
-	for y := 0; y < w.Height; y++ {
-		for x := 0; x < w.Width; x++ {
-			if organism := w.Grid[y][x]; organism != nil {
-				stats[organism.GetType()]++
-			}
-		}
+func (w *World) Clone() *World {
+	clone := *w
+	rng := *w.rng
+	clone.rng = &rng
+	clone.grid = NewSparseGrid[Organism](w.Width)
+	clone.terrain = w.terrain.Clone()
+	clone.buffer = nil
+	clone.actions = nil
+	clone.EventLog = append([]LogEntry(nil), w.EventLog...)
+	clone.births = copyCounts(w.births)
+	clone.arrivals = copyCounts(w.arrivals)
+	clone.deaths = copyCounts(w.deaths)
+	clone.causes = copyCounts(w.causes)
+	clone.meals = copyCounts(w.meals)
+	if w.metrics != nil {
+		clone.metrics = &metricsRecorder{}
+	}
+
+	clones := make(map[int]Organism, len(w.index.All()))
+	for _, organism := range w.index.All() {
+		organismClone := organism.Clone()
+		clones[organism.GetID()] = organismClone
+		x, y := organismClone.GetPosition()
+		clone.grid.Set(x, y, organismClone)
 	}
-	return stats
+	clone.index = w.index.cloneWith(clones)
+	return &clone
 }
 
-func (w *World) GetOrganismsByType(organismType string) []Organism {
-	var organisms []Organism
-	for y := 0; y < w.Height; y++ {
-		for x := 0; x < w.Width; x++ {
-			if organism := w.Grid[y][x]; organism != nil && organism.GetType() == organismType {
-				organisms = append(organisms, organism)
-			}
-		}
+func (w *World) ForEachOrganism(fn func(Organism)) {
+	for _, organism := range w.index.All() {
+		fn(organism)
 	}
-	return organisms
+}
+
+func (w *World) CountOrganisms(organismType string) int {
+	return w.index.Count(organismType)
+}
+
+func (w *World) GetOrganismsByType(organismType string) []Organism {
+	return append([]Organism(nil), w.index.ByType(organismType)...)
 }
 
 func (w *World) Simulate() {
-	organisms := w.getAllLivingOrganisms()
-	rand.Shuffle(len(organisms), func(i, j int) {
-		organisms[i], organisms[j] = organisms[j], organisms[i]
-	})
+	turnStart := w.metrics.start()
+	clear(w.births)
+	clear(w.arrivals)
+	clear(w.deaths)
+	clear(w.causes)
+	clear(w.meals)
+	if w.Trajectories != nil {
+		w.actions = make(map[int][]ActionKind)
+	}
+	w.applyScenario()
+	if w.UpdateMode == SynchronousUpdate {
+		w.simulateSynchronous()
+	} else {
+		w.simulateSequential()
+	}
 
-	for _, organism := range organisms {
-		if organism.GetEnergy() <= 0 {
-			continue
+	start := w.metrics.start()
+	w.updateAndCleanup()
+	w.metrics.stop(CleanupPhase, start)
+	if w.GrassSpawnInterval > 0 && w.Turn%w.GrassSpawnInterval == 0 {
+		start = w.metrics.start()
+		w.spawnRandomGrass(w.grassSpawnCount())
+		w.metrics.stop(GrassPhase, start)
+	}
+	if w.metrics != nil {
+		w.lastMetrics = w.metrics.collect(w.Turn, turnStart)
+	}
+	w.Turn++
+	w.History.Record(w)
+	w.Lineage.Record(w)
+	w.Trajectories.Record(w)
+	w.Occupancy.Record(w)
+}
+
+func (w *World) planEating(organism Organism) Organism {
+	if organism.GetEatingCooldown() != 0 || organism.GetActionPoints() < organism.GetTraits().EatCost {
+		return nil
+	}
+	x, y := organism.GetPosition()
+	if food := w.FindFood(x, y, organism.GetDiet()); len(food) > 0 {
+		return food[0]
+	}
+	return nil
+}
+
+func (w *World) eat(organism, food Organism) bool {
+	if food.GetEnergy() <= 0 || w.GetOrganism(food.GetPosition()) != food {
+		return false
+	}
+	switch org := organism.(type) {
+	case *Rabbit:
+		if org.GetEatingCooldown() != 0 {
+			return false
 		}
+		org.Eat()
+	case *Fox:
+		if org.GetEatingCooldown() != 0 {
+			return false
+		}
+		org.Eat()
+	default:
+		return false
+	}
+	fx, fy := food.GetPosition()
+	w.kill(food, EatenDeath)
+	w.meals[mealKey{organism.GetType(), food.GetType()}]++
+	organism.GetMemory().Remember(fx, fy, FoodMemory, w.Turn)
+	return true
+}
 
-		x, y := organism.GetPosition()
-		if food := w.FindFood(x, y, organism.GetDiet()); len(food) > 0 {
-			switch org := organism.(type) {
-			case *Rabbit:
-				if org.GetEatingCooldown() == 0 {
-					org.Eat()
-					fx, fy := food[0].GetPosition()
-					w.RemoveOrganism(fx, fy)
-				}
-			case *Fox:
-				if org.GetEatingCooldown() == 0 {
-					org.Eat()
-					fx, fy := food[0].GetPosition()
-					w.RemoveOrganism(fx, fy)
-				}
-			}
+func (w *World) planWander(organism Organism, rng randSource) ([2]int, bool) {
+	x, y := organism.GetPosition()
+	positions := w.GetEmptyNeighborPositions(x, y)
+	if len(positions) == 0 {
+		return [2]int{}, false
+	}
+	memory := organism.GetMemory()
+	weights := make([]float64, len(positions))
+	total := 0.0
+	for i, pos := range positions {
+		weights[i] = 1 / (1 + 4*memory.DangerAt(pos[0], pos[1]))
+		total += weights[i]
+	}
+	pick := rng.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return positions[i], true
 		}
-		if organism.GetEnergy() > 0 && organism.CanMove() {
-			moved := false
-			if organism.CanBreed() && !organism.HasBred() && organism.GetType() != "Grass" {
-				moved = w.moveTowardsPartner(organism)
-			}
-			if !moved {
-				if positions := w.GetEmptyNeighborPositions(x, y); len(positions) > 0 {
-					newPos := positions[rand.Intn(len(positions))]
-					w.MoveOrganism(x, y, newPos[0], newPos[1])
-				}
-			}
+		pick -= weight
+	}
+	return positions[len(positions)-1], true
+}
+
+func (w *World) planStepTowards(organism Organism, targetX, targetY int) ([2]int, bool) {
+	x, y := organism.GetPosition()
+	bestDistance := squaredDistance(x, y, targetX, targetY)
+	var best [2]int
+	found := false
+	for _, pos := range w.GetEmptyNeighborPositions(x, y) {
+		if distance := squaredDistance(pos[0], pos[1], targetX, targetY); distance < bestDistance {
+			bestDistance = distance
+			best = pos
+			found = true
 		}
-		if organism.GetEnergy() > 0 && organism.CanBreed() && !organism.HasBred() {
-			w.tryBreeding(organism)
+	}
+	return best, found
+}
+
+func (w *World) planStepAway(organism Organism, threats []Organism) ([2]int, bool) {
+	x, y := organism.GetPosition()
+	bestDistance := closestThreatDistance(x, y, threats)
+	var best [2]int
+	found := false
+	for _, pos := range w.GetEmptyNeighborPositions(x, y) {
+		if distance := closestThreatDistance(pos[0], pos[1], threats); distance > bestDistance {
+			bestDistance = distance
+			best = pos
+			found = true
 		}
 	}
+	return best, found
+}
 
-	w.updateAndCleanup()
-	if w.Turn%5 == 0 {
-		w.spawnRandomGrass(5)
+func closestThreatDistance(x, y int, threats []Organism) int {
+	closest := -1
+	for _, threat := range threats {
+		tx, ty := threat.GetPosition()
+		if distance := squaredDistance(x, y, tx, ty); closest < 0 || distance < closest {
+			closest = distance
+		}
 	}
-	w.Turn++
+	return closest
 }
 
-func (w *World) tryBreeding(organism Organism) {
+func (w *World) planBreeding(organism Organism, rng randSource) (Organism, [2]int, bool) {
+	traits := organism.GetTraits()
+	if organism.GetEnergy() <= traits.HungerThreshold || organism.GetState() == Fleeing ||
+		!organism.CanBreed() || organism.HasBred() ||
+		organism.GetActionPoints() < traits.BreedCost {
+		return nil, [2]int{}, false
+	}
 	x, y := organism.GetPosition()
 	emptyPositions := w.GetEmptyNeighborPositions(x, y)
-
-	if len(emptyPositions) == 0 {
-		return
+	if len(emptyPositions) == 0 || organism.GetEnergy() < traits.BreedEnergy {
+		return nil, [2]int{}, false
 	}
+	newPos := emptyPositions[rng.Intn(len(emptyPositions))]
 	if organism.GetType() == "Grass" {
-		if organism.GetEnergy() >= 4 {
-			organism.Breed()
-			newPos := emptyPositions[rand.Intn(len(emptyPositions))]
-			newGrass := NewGrass(w.nextID, newPos[0], newPos[1])
-			w.PlaceOrganism(newGrass)
-			w.nextID++
-		}
-		return
+		return nil, newPos, true
 	}
 	partner := w.findNearbyPartner(organism, x, y)
-	if partner == nil {
-		return
+	if partner == nil || partner.GetEnergy() < partner.GetTraits().BreedEnergy {
+		return nil, [2]int{}, false
 	}
+	return partner, newPos, true
+}
 
-	minEnergy := 3
-	if organism.GetType() == "Fox" {
-		minEnergy = 4
+func (w *World) breed(organism, partner Organism, x, y int) bool {
+	if !w.IsEmpty(x, y) {
+		return false
+	}
+	if partner != nil && (!partner.CanBreed() || partner.HasBred() || partner.GetEnergy() <= 0) {
+		return false
 	}
 
-	if organism.GetEnergy() >= minEnergy && partner.GetEnergy() >= minEnergy {
-		organism.Breed()
-		partner.Breed()
-
-		newPos := emptyPositions[rand.Intn(len(emptyPositions))]
-		var newOrganism Organism
-
-		if organism.GetType() == "Rabbit" {
-			newOrganism = NewRabbit(w.nextID, newPos[0], newPos[1])
-		} else if organism.GetType() == "Fox" {
-			newOrganism = NewFox(w.nextID, newPos[0], newPos[1])
-		}
+	newOrganism := NewOrganism(organism.GetType(), w.nextID, x, y)
+	if newOrganism == nil {
+		return false
+	}
 
-		if newOrganism != nil {
-			w.PlaceOrganism(newOrganism)
-			w.nextID++
-		}
+	parents := []int{organism.GetID()}
+	organism.Breed()
+	if partner != nil {
+		partner.Breed()
+		parents = append(parents, partner.GetID())
 	}
+	newOrganism.SetLineage(parents, w.Turn)
+	w.PlaceOrganism(newOrganism)
+	w.births[newOrganism.GetType()]++
+	w.nextID++
+	return true
 }
 
 func (w *World) findNearbyPartner(organism Organism, x, y int) Organism {
+	w.metrics.count(PartnerSearches)
 	directions := [][]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
 
 	for _, dir := range directions {
@@ -244,23 +411,23 @@ func (w *World) findNearbyPartner(organism Organism, x, y int) Organism {
 }
 
 func (w *World) getAllLivingOrganisms() []Organism {
-	var organisms []Organism
-	for y := 0; y < w.Height; y++ {
-		for x := 0; x < w.Width; x++ {
-			if organism := w.Grid[y][x]; organism != nil && organism.GetEnergy() > 0 {
-				organisms = append(organisms, organism)
-			}
+	w.buffer = w.buffer[:0]
+	for _, organism := range w.index.All() {
+		if organism.GetEnergy() > 0 {
+			w.buffer = append(w.buffer, organism)
 		}
 	}
-	return organisms
+	return w.buffer
 }
 func (w *World) spawnRandomGrass(count int) {
 	for i := 0; i < count; i++ {
 		for attempts := 0; attempts < 50; attempts++ {
-			x, y := rand.Intn(w.Width), rand.Intn(w.Height)
+			x, y := w.rng.Intn(w.Width), w.rng.Intn(w.Height)
 			if w.IsEmpty(x, y) {
 				grass := NewGrass(w.nextID, x, y)
+				grass.SetLineage(nil, w.Turn)
 				w.PlaceOrganism(grass)
+				w.arrivals["Grass"]++
 				w.nextID++
 				break
 			}
@@ -269,113 +436,41 @@ func (w *World) spawnRandomGrass(count int) {
 }
 
 func (w *World) updateAndCleanup() {
-	for y := 0; y < w.Height; y++ {
-		for x := 0; x < w.Width; x++ {
-			if organism := w.Grid[y][x]; organism != nil {
-				if organism.GetEnergy() > 0 {
-					organism.NewTurn()
-				}
-				if organism.GetEnergy() <= 0 {
-					w.Grid[y][x] = nil
-				}
-			}
+	organisms := append(w.buffer[:0], w.index.All()...)
+	w.parallelFor(len(organisms), func(i int) {
+		if organisms[i].GetEnergy() > 0 {
+			organisms[i].NewTurn()
+		}
+	})
+	for _, organism := range organisms {
+		if organism.GetEnergy() <= 0 {
+			w.kill(organism, StarvationDeath)
 		}
 	}
+	w.buffer = organisms
 }
 
-func (w *World) PopulateRandomly(foxCount, rabbitCount, grassCount int) {
-	for i := 0; i < foxCount; i++ {
-		for attempts := 0; attempts < 100; attempts++ {
-			x, y := rand.Intn(w.Width), rand.Intn(w.Height)
-			if w.IsEmpty(x, y) {
-				fox := NewFox(w.nextID, x, y)
-				w.PlaceOrganism(fox)
-				w.nextID++
-				break
-			}
-		}
-	}
-	for i := 0; i < rabbitCount; i++ {
-		for attempts := 0; attempts < 100; attempts++ {
-			x, y := rand.Intn(w.Width), rand.Intn(w.Height)
-			if w.IsEmpty(x, y) {
-				rabbit := NewRabbit(w.nextID, x, y)
-				w.PlaceOrganism(rabbit)
-				w.nextID++
-				break
-			}
-		}
-	}
-	for i := 0; i < grassCount; i++ {
-		for attempts := 0; attempts < 100; attempts++ {
-			x, y := rand.Intn(w.Width), rand.Intn(w.Height)
-			if w.IsEmpty(x, y) {
-				grass := NewGrass(w.nextID, x, y)
-				w.PlaceOrganism(grass)
-				w.nextID++
-				break
-			}
-		}
-	}
+func (w *World) PopulateRandomly(foxCount, rabbitCount, grassCount int) PlacementReport {
+	return w.Populate(UniformPlacement, map[string]int{"Fox": foxCount, "Rabbit": rabbitCount, "Grass": grassCount})
 }
 
 func (w *World) IsExtinct() bool {
-	stats := w.GetStatistics()
-	return stats["Fox"] == 0 && stats["Rabbit"] == 0
+	return w.index.Count("Fox") == 0 && w.index.Count("Rabbit") == 0
 }
 
-func (w *World) moveTowardsPartner(organism Organism) bool {
-	x, y := organism.GetPosition()
-	orgType := organism.GetType()
-	var closestPartner Organism
-	var closestDistance int = 100
-
-	for dy := -3; dy <= 3; dy++ {
-		for dx := -3; dx <= 3; dx++ {
-			if dx == 0 && dy == 0 {
-				continue
-			}
-
-			nx, ny := x+dx, y+dy
-			if !w.IsValidPosition(nx, ny) {
-				continue
-			}
-
-			if partner := w.GetOrganism(nx, ny); partner != nil {
-				if partner.GetType() == orgType &&
-					partner.CanBreed() &&
-					!partner.HasBred() &&
-					partner.GetEnergy() > 0 {
-					distance := dx*dx + dy*dy
-					if distance < closestDistance {
-						closestDistance = distance
-						closestPartner = partner
-					}
-				}
-			}
-		}
-	}
-
+func (w *World) planPartnerApproach(organism Organism) ([2]int, bool) {
+	closestPartner := nearest(organism, w.FindVisiblePartners(organism))
 	if closestPartner == nil {
-		return false
+		return [2]int{}, false
 	}
 	px, py := closestPartner.GetPosition()
-	var moveX, moveY int = x, y
-	if px > x {
-		moveX = x + 1
-	} else if px < x {
-		moveX = x - 1
-	}
+	return w.planStepTowards(organism, px, py)
+}
 
-	if py > y {
-		moveY = y + 1
-	} else if py < y {
-		moveY = y - 1
+func copyCounts[K comparable](counts map[K]int) map[K]int {
+	clone := make(map[K]int, len(counts))
+	for key, count := range counts {
+		clone[key] = count
 	}
-	if w.IsEmpty(moveX, moveY) {
-		w.MoveOrganism(x, y, moveX, moveY)
-		return true
-	}
-
-	return false
+	return clone
 }
@@ -0,0 +1,70 @@
+package main
+
+type Terrain int
+
+const (
+	Open Terrain = iota
+	Obstacle
+	Cover
+)
+
+func (t Terrain) GetIcon() string {
+	switch t {
+	case Obstacle:
+		return "🪨"
+	case Cover:
+		return "🌳"
+	}
+	return "⬜"
+}
+
+func (t Terrain) BlocksMovement() bool {
+	return t == Obstacle
+}
+
+func (t Terrain) BlocksSight() bool {
+	return t == Obstacle || t == Cover
+}
+
+func (w *World) GetTerrain(x, y int) Terrain {
+	if !w.IsValidPosition(x, y) {
+		return Obstacle
+	}
+	return w.terrain.Get(x, y)
+}
+
+func (w *World) SetTerrain(x, y int, terrain Terrain) bool {
+	if !w.IsValidPosition(x, y) {
+		return false
+	}
+	if terrain.BlocksMovement() && w.grid.Get(x, y) != nil {
+		return false
+	}
+	w.terrain.Set(x, y, terrain)
+	return true
+}
+
+func (w *World) GenerateTerrain(obstacleCount, hedgerowCount int) {
+	for i := 0; i < obstacleCount; i++ {
+		for attempts := 0; attempts < 100; attempts++ {
+			x, y := w.rng.Intn(w.Width), w.rng.Intn(w.Height)
+			if w.IsEmpty(x, y) && w.SetTerrain(x, y, Obstacle) {
+				break
+			}
+		}
+	}
+	for i := 0; i < hedgerowCount; i++ {
+		x, y := w.rng.Intn(w.Width), w.rng.Intn(w.Height)
+		dx, dy := 1, 0
+		if w.rng.Intn(2) == 0 {
+			dx, dy = 0, 1
+		}
+		length := 4 + w.rng.Intn(5)
+		for j := 0; j < length && w.IsValidPosition(x, y); j++ {
+			if w.GetTerrain(x, y) == Open {
+				w.SetTerrain(x, y, Cover)
+			}
+			x, y = x+dx, y+dy
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type TrajectoryPoint struct {
+	Turn    int
+	X, Y    int
+	Energy  int
+	Actions []ActionKind
+}
+
+func (p TrajectoryPoint) ActionString() string {
+	if len(p.Actions) == 0 {
+		return StayAction.String()
+	}
+	parts := make([]string, len(p.Actions))
+	for i, action := range p.Actions {
+		parts[i] = action.String()
+	}
+	return strings.Join(parts, "+")
+}
+
+type Trajectory struct {
+	ID     int
+	Type   string
+	Points []TrajectoryPoint
+}
+
+type Trajectories struct {
+	Selected map[int]bool
+	Paths    map[int]*Trajectory
+}
+
+func NewTrajectories(ids ...int) *Trajectories {
+	t := &Trajectories{Selected: make(map[int]bool), Paths: make(map[int]*Trajectory)}
+	for _, id := range ids {
+		t.Selected[id] = true
+	}
+	return t
+}
+
+func ParseTrajectoryIDs(spec string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "all" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("niepoprawny identyfikator %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (t *Trajectories) tracks(organism Organism) bool {
+	if len(t.Selected) > 0 {
+		return t.Selected[organism.GetID()]
+	}
+	return organism.GetType() != "Grass"
+}
+
+func (t *Trajectories) Record(w *World) {
+	if t == nil {
+		return
+	}
+	for id, path := range t.Paths {
+		kept := len(path.Points)
+		for kept > 0 && path.Points[kept-1].Turn >= w.Turn {
+			kept--
+		}
+		path.Points = path.Points[:kept]
+		if kept == 0 {
+			delete(t.Paths, id)
+		}
+	}
+	w.ForEachOrganism(func(organism Organism) {
+		if !t.tracks(organism) {
+			return
+		}
+		path, ok := t.Paths[organism.GetID()]
+		if !ok {
+			path = &Trajectory{ID: organism.GetID(), Type: organism.GetType()}
+			t.Paths[organism.GetID()] = path
+		}
+		x, y := organism.GetPosition()
+		path.Points = append(path.Points, TrajectoryPoint{
+			Turn:    w.Turn,
+			X:       x,
+			Y:       y,
+			Energy:  organism.GetEnergy(),
+			Actions: w.actions[organism.GetID()],
+		})
+	})
+}
+
+func (t *Trajectories) Until(turn int) *Trajectories {
+	if t == nil {
+		return nil
+	}
+	view := &Trajectories{Selected: t.Selected, Paths: make(map[int]*Trajectory, len(t.Paths))}
+	for id, path := range t.Paths {
+		points := path.Points[:sort.Search(len(path.Points), func(i int) bool { return path.Points[i].Turn > turn })]
+		if len(points) > 0 {
+			view.Paths[id] = &Trajectory{ID: path.ID, Type: path.Type, Points: points}
+		}
+	}
+	return view
+}
+
+func (t *Trajectories) Sorted() []*Trajectory {
+	paths := make([]*Trajectory, 0, len(t.Paths))
+	for _, path := range t.Paths {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].ID < paths[j].ID })
+	return paths
+}
+
+func (t *Trajectories) WriteCSV(out io.Writer) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"id", "type", "turn", "x", "y", "energy", "actions"}); err != nil {
+		return err
+	}
+	for _, path := range t.Sorted() {
+		for _, point := range path.Points {
+			err := writer.Write([]string{
+				strconv.Itoa(path.ID),
+				path.Type,
+				strconv.Itoa(point.Turn),
+				strconv.Itoa(point.X),
+				strconv.Itoa(point.Y),
+				strconv.Itoa(point.Energy),
+				point.ActionString(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (t *Trajectories) WriteGeoJSON(out io.Writer) error {
+	type geometry struct {
+		Type        string `json:"type"`
+		Coordinates any    `json:"coordinates"`
+	}
+	type feature struct {
+		Type       string         `json:"type"`
+		Geometry   geometry       `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	}
+	collection := struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}{Type: "FeatureCollection", Features: []feature{}}
+
+	for _, path := range t.Sorted() {
+		coordinates := make([][2]int, len(path.Points))
+		turns := make([]int, len(path.Points))
+		energy := make([]int, len(path.Points))
+		actions := make([]string, len(path.Points))
+		for i, point := range path.Points {
+			coordinates[i] = [2]int{point.X, point.Y}
+			turns[i], energy[i], actions[i] = point.Turn, point.Energy, point.ActionString()
+		}
+		shape := geometry{Type: "LineString", Coordinates: coordinates}
+		if len(coordinates) == 1 {
+			shape = geometry{Type: "Point", Coordinates: coordinates[0]}
+		}
+		collection.Features = append(collection.Features, feature{
+			Type:     "Feature",
+			Geometry: shape,
+			Properties: map[string]any{
+				"id":      path.ID,
+				"type":    path.Type,
+				"turns":   turns,
+				"energy":  energy,
+				"actions": actions,
+			},
+		})
+	}
+	encoder := json.NewEncoder(out)
+	return encoder.Encode(collection)
+}
+
+func (t *Trajectories) Export(out io.Writer, format string) error {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "csv":
+		return t.WriteCSV(out)
+	case "geojson", "json":
+		return t.WriteGeoJSON(out)
+	}
+	return fmt.Errorf("nieznany format trajektorii %q", format)
+}
+
+func (t *Trajectories) Trail(id, length, last int) []TrajectoryPoint {
+	if t == nil || t.Paths[id] == nil {
+		return nil
+	}
+	points := t.Paths[id].Points
+	points = points[:sort.Search(len(points), func(i int) bool { return points[i].Turn > last })]
+	return points[max(0, len(points)-length):]
+}
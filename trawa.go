@@ -3,6 +3,7 @@ package main
 import "fmt"
 
 type Grass struct {
+	actor
 	energy           int
 	ID               int
 	icon             string
@@ -19,6 +20,7 @@ type Grass struct {
 
 func NewGrass(id int, x int, y int) *Grass {
 	return &Grass{
+		actor:            newActor("Grass"),
 		ID:               id,
 		canEat:           false,
 		energy:           6,
@@ -34,7 +36,7 @@ func NewGrass(id int, x int, y int) *Grass {
 }
 
 func (r *Grass) PrintInfo() {
-	fmt.Printf("ID: %d\nEnergy: %d\nPosition: (%d,%d)\nAte: %t\nCan Breed: %t\n", r.ID, r.energy, r.x, r.y, r.ate, r.canBreed)
+	fmt.Printf("ID: %d\nEnergy: %d\nPosition: (%d,%d)\nAte: %t\nCan Breed: %t\nState: %s\n", r.ID, r.energy, r.x, r.y, r.ate, r.canBreed, r.state)
 }
 
 func (r *Grass) GetIcon() string {
@@ -90,13 +92,23 @@ func (r *Grass) Breed() {
 	}
 }
 
+func (r *Grass) Clone() Organism {
+	clone := *r
+	clone.actor = r.actor.clone()
+	return &clone
+}
+
 func (r *Grass) Move(x int, y int) {
 }
+func (r *Grass) UseEnergy(amount int) {
+	r.energy -= amount
+}
 func (r *Grass) Die() {
 	r.energy = 0
 }
 
 func (r *Grass) NewTurn() {
+	r.startTurn()
 	if r.breedingCooldown > 0 {
 		r.breedingCooldown--
 		if r.breedingCooldown == 0 {
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+const (
+	chartWidth  = 6 * vg.Inch
+	chartHeight = 4.5 * vg.Inch
+)
+
+var chartSeries = []struct {
+	organismType string
+	label        string
+	color        color.RGBA
+}{
+	{"Fox", "Lisy", color.RGBA{R: 255, G: 100, B: 0, A: 255}},
+	{"Rabbit", "Króliki", color.RGBA{R: 139, G: 69, B: 19, A: 255}},
+	{"Grass", "Trawa", color.RGBA{R: 0, G: 128, B: 0, A: 255}},
+}
+
+func NewPopulationChart(records []TurnRecord) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = "Populacja w czasie"
+	p.X.Label.Text = "Tura"
+	p.Y.Label.Text = "Liczba organizmów"
+
+	for _, series := range chartSeries {
+		points := make(plotter.XYs, len(records))
+		for i, record := range records {
+			points[i].X = float64(record.Turn)
+			points[i].Y = float64(record.Counts[series.organismType])
+		}
+		if len(records) >= 2 {
+			line, _ := plotter.NewLine(points)
+			line.Color = series.color
+			line.Width = vg.Points(2)
+			p.Add(line)
+			p.Legend.Add(series.label, line)
+		} else {
+			scatter, _ := plotter.NewScatter(points)
+			scatter.Color = series.color
+			p.Add(scatter)
+			p.Legend.Add(series.label, scatter)
+		}
+	}
+	return p
+}
+
+func AddFitOverlay(p *plot.Plot, fit *LotkaVolterraFit, records []TurnRecord) {
+	if fit == nil || len(records) == 0 {
+		return
+	}
+	from, to := records[0].Turn, records[len(records)-1].Turn
+	for _, series := range []struct {
+		values []float64
+		label  string
+		color  color.RGBA
+	}{
+		{fit.Foxes, "Lisy (L-V)", chartSeries[0].color},
+		{fit.Rabbits, "Króliki (L-V)", chartSeries[1].color},
+	} {
+		var points plotter.XYs
+		for i, value := range series.values {
+			if turn := fit.FromTurn + i; turn >= from && turn <= to {
+				points = append(points, plotter.XY{X: float64(turn), Y: value})
+			}
+		}
+		if len(points) < 2 {
+			continue
+		}
+		line, _ := plotter.NewLine(points)
+		line.Color = series.color
+		line.Width = vg.Points(1.5)
+		line.Dashes = []vg.Length{vg.Points(6), vg.Points(4)}
+		p.Add(line)
+		p.Legend.Add(series.label, line)
+	}
+}
+
+func WriteChart(p *plot.Plot, out io.Writer, format string, dpi int) error {
+	format = strings.ToLower(strings.TrimPrefix(format, "."))
+	switch format {
+	case "png":
+		canvas := vgimg.NewWith(vgimg.UseWH(chartWidth, chartHeight), vgimg.UseDPI(dpi))
+		p.Draw(draw.New(canvas))
+		_, err := vgimg.PngCanvas{Canvas: canvas}.WriteTo(out)
+		return err
+	case "svg", "pdf", "eps":
+		writer, err := p.WriterTo(chartWidth, chartHeight, format)
+		if err != nil {
+			return err
+		}
+		_, err = writer.WriteTo(out)
+		return err
+	}
+	return fmt.Errorf("nieznany format wykresu %q (svg, pdf, eps, png)", format)
+}
+
+func (h *History) Window(turns, last int) []TurnRecord {
+	records := h.Until(last)
+	return records[max(0, len(records)-turns):]
+}
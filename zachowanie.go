@@ -0,0 +1,113 @@
+package main
+
+type BehaviorState int
+
+const (
+	Resting BehaviorState = iota
+	Foraging
+	SeekingMate
+	Fleeing
+)
+
+func (s BehaviorState) String() string {
+	switch s {
+	case Foraging:
+		return "Żerowanie"
+	case SeekingMate:
+		return "Szukanie partnera"
+	case Fleeing:
+		return "Ucieczka"
+	}
+	return "Odpoczynek"
+}
+
+func (w *World) chooseState(organism Organism) BehaviorState {
+	if !organism.CanMove() {
+		return Resting
+	}
+	traits := organism.GetTraits()
+	if w.nearestThreat(organism, traits.FleeDistance) != nil {
+		return Fleeing
+	}
+	if organism.GetEnergy() <= traits.HungerThreshold {
+		if organism.GetEatingCooldown() == 0 {
+			return Foraging
+		}
+		return Resting
+	}
+	if organism.CanBreed() && !organism.HasBred() && organism.GetEnergy() >= traits.MateThreshold {
+		return SeekingMate
+	}
+	if organism.GetEatingCooldown() == 0 {
+		return Foraging
+	}
+	return Resting
+}
+
+func (w *World) observe(organism Organism) {
+	memory := organism.GetMemory()
+	if memory == nil {
+		return
+	}
+	for _, target := range w.Perceive(organism) {
+		tx, ty := target.GetPosition()
+		if eats(organism, target) {
+			memory.Remember(tx, ty, FoodMemory, w.Turn)
+		} else if eats(target, organism) {
+			memory.Remember(tx, ty, DangerMemory, w.Turn)
+		}
+	}
+}
+
+func (w *World) nearestThreat(organism Organism, maxDistance int) Organism {
+	threat := nearest(organism, w.FindVisiblePredators(organism))
+	if threat == nil || maxDistance <= 0 {
+		return threat
+	}
+	x, y := organism.GetPosition()
+	tx, ty := threat.GetPosition()
+	if squaredDistance(x, y, tx, ty) > maxDistance*maxDistance {
+		return nil
+	}
+	return threat
+}
+
+func (w *World) planMove(organism Organism, state BehaviorState, rng randSource) ([2]int, bool) {
+	switch state {
+	case Resting:
+		return [2]int{}, false
+	case Fleeing:
+		if pos, ok := w.planStepAway(organism, w.FindVisiblePredators(organism)); ok {
+			return pos, true
+		}
+	case SeekingMate:
+		if pos, ok := w.planPartnerApproach(organism); ok {
+			return pos, true
+		}
+	case Foraging:
+		if food := nearest(organism, w.FindVisibleFood(organism)); food != nil {
+			fx, fy := food.GetPosition()
+			if pos, ok := w.planStepTowards(organism, fx, fy); ok {
+				return pos, true
+			}
+		} else if pos, ok := w.planRememberedFoodApproach(organism); ok {
+			return pos, true
+		}
+	}
+	return w.planWander(organism, rng)
+}
+
+func (w *World) planRememberedFoodApproach(organism Organism) ([2]int, bool) {
+	memory := organism.GetMemory()
+	for {
+		entry, ok := memory.Strongest(FoodMemory)
+		if !ok {
+			return [2]int{}, false
+		}
+		x, y := organism.GetPosition()
+		if squaredDistance(x, y, entry.X, entry.Y) > 2 {
+			return w.planStepTowards(organism, entry.X, entry.Y)
+		}
+		memory.Forget(entry.X, entry.Y, FoodMemory)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StopCondition interface {
+	Check(w *World) (bool, string)
+}
+
+type Termination struct {
+	Reason string
+	Turn   int
+}
+
+func (t Termination) String() string {
+	return fmt.Sprintf("%s (tura %d)", t.Reason, t.Turn)
+}
+
+func CheckStop(condition StopCondition, w *World) (Termination, bool) {
+	if condition == nil {
+		return Termination{}, false
+	}
+	if stop, reason := condition.Check(w); stop {
+		return Termination{Reason: reason, Turn: w.Turn}, true
+	}
+	return Termination{}, false
+}
+
+type AnyExtinct struct {
+	Species []string
+}
+
+func (c AnyExtinct) Check(w *World) (bool, string) {
+	for _, species := range c.Species {
+		if w.CountOrganisms(species) == 0 {
+			return true, fmt.Sprintf("wymarł gatunek %s", species)
+		}
+	}
+	return false, ""
+}
+
+type AllExtinct struct {
+	Species []string
+}
+
+func (c AllExtinct) Check(w *World) (bool, string) {
+	for _, species := range c.Species {
+		if w.CountOrganisms(species) > 0 {
+			return false, ""
+		}
+	}
+	return true, fmt.Sprintf("wymarły gatunki %s", strings.Join(c.Species, ", "))
+}
+
+type TurnLimit struct {
+	Turns int
+}
+
+func (c TurnLimit) Check(w *World) (bool, string) {
+	if w.Turn >= c.Turns {
+		return true, fmt.Sprintf("osiągnięto limit %d tur", c.Turns)
+	}
+	return false, ""
+}
+
+type PopulationAbove struct {
+	Species   string
+	Threshold int
+}
+
+func (c PopulationAbove) Check(w *World) (bool, string) {
+	if count := w.CountOrganisms(c.Species); count > c.Threshold {
+		return true, fmt.Sprintf("populacja %s przekroczyła %d (%d)", c.Species, c.Threshold, count)
+	}
+	return false, ""
+}
+
+type PopulationBelow struct {
+	Species   string
+	Threshold int
+}
+
+func (c PopulationBelow) Check(w *World) (bool, string) {
+	if count := w.CountOrganisms(c.Species); count < c.Threshold {
+		return true, fmt.Sprintf("populacja %s spadła poniżej %d (%d)", c.Species, c.Threshold, count)
+	}
+	return false, ""
+}
+
+type SteadyState struct {
+	Window    int
+	Tolerance float64
+	history   [][]int
+}
+
+func (c *SteadyState) Check(w *World) (bool, string) {
+	if c.Window <= 1 {
+		return false, ""
+	}
+	counts := make([]int, len(placementOrder))
+	for i, species := range placementOrder {
+		counts[i] = w.CountOrganisms(species)
+	}
+	c.history = append(c.history, counts)
+	if len(c.history) > c.Window {
+		c.history = c.history[len(c.history)-c.Window:]
+	}
+	if len(c.history) < c.Window {
+		return false, ""
+	}
+	for i := range placementOrder {
+		low, high, sum := math.MaxInt, 0, 0
+		for _, counts := range c.history {
+			low, high, sum = min(low, counts[i]), max(high, counts[i]), sum+counts[i]
+		}
+		mean := float64(sum) / float64(len(c.history))
+		if float64(high-low) > c.Tolerance*mean {
+			return false, ""
+		}
+	}
+	return true, fmt.Sprintf("stan ustalony przez %d tur", c.Window)
+}
+
+type WallClock struct {
+	Limit   time.Duration
+	started time.Time
+	elapsed time.Duration
+	paused  bool
+}
+
+func (c *WallClock) Check(w *World) (bool, string) {
+	elapsed := c.elapsed
+	if !c.paused {
+		if c.started.IsZero() {
+			c.started = time.Now()
+		}
+		elapsed += time.Since(c.started)
+	}
+	if elapsed >= c.Limit {
+		return true, fmt.Sprintf("przekroczono limit czasu %v", c.Limit)
+	}
+	return false, ""
+}
+
+func (c *WallClock) Pause() {
+	if !c.paused && !c.started.IsZero() {
+		c.elapsed += time.Since(c.started)
+	}
+	c.paused = true
+}
+
+func (c *WallClock) Resume() {
+	c.started = time.Now()
+	c.paused = false
+}
+
+func SetClocksRunning(condition StopCondition, running bool) {
+	switch c := condition.(type) {
+	case *WallClock:
+		if running {
+			c.Resume()
+		} else {
+			c.Pause()
+		}
+	case AnyOf:
+		for _, condition := range c {
+			SetClocksRunning(condition, running)
+		}
+	case AllOf:
+		for _, condition := range c {
+			SetClocksRunning(condition, running)
+		}
+	}
+}
+
+type AnyOf []StopCondition
+
+func (c AnyOf) Check(w *World) (bool, string) {
+	for _, condition := range c {
+		if stop, reason := condition.Check(w); stop {
+			return true, reason
+		}
+	}
+	return false, ""
+}
+
+type AllOf []StopCondition
+
+func (c AllOf) Check(w *World) (bool, string) {
+	var reasons []string
+	all := len(c) > 0
+	for _, condition := range c {
+		stop, reason := condition.Check(w)
+		all = all && stop
+		reasons = append(reasons, reason)
+	}
+	if !all {
+		return false, ""
+	}
+	return true, strings.Join(reasons, " i ")
+}
+
+func ParseStopConditions(spec string) (AnyOf, error) {
+	var any AnyOf
+	for _, alternative := range strings.Split(spec, ",") {
+		alternative = strings.TrimSpace(alternative)
+		if alternative == "" {
+			continue
+		}
+		var all AllOf
+		for _, part := range strings.Split(alternative, "&") {
+			condition, err := parseStopCondition(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, condition)
+		}
+		if len(all) == 1 {
+			any = append(any, all[0])
+		} else {
+			any = append(any, all)
+		}
+	}
+	return any, nil
+}
+
+func parseStopCondition(text string) (StopCondition, error) {
+	fields := strings.Split(text, ":")
+	argument := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	number := func(i int) (int, error) {
+		value, err := strconv.Atoi(argument(i))
+		if err != nil {
+			return 0, fmt.Errorf("warunek %q: niepoprawna liczba %q", text, argument(i))
+		}
+		return value, nil
+	}
+	species := func(i int) ([]string, error) {
+		if argument(i) == "" {
+			return []string{"Fox", "Rabbit"}, nil
+		}
+		names := strings.Split(argument(i), "+")
+		for _, name := range names {
+			if NewOrganism(name, 0, 0, 0) == nil {
+				return nil, fmt.Errorf("warunek %q: nieznany gatunek %q", text, name)
+			}
+		}
+		return names, nil
+	}
+
+	switch fields[0] {
+	case "extinct":
+		names, err := species(1)
+		return AnyExtinct{Species: names}, err
+	case "allextinct":
+		names, err := species(1)
+		return AllExtinct{Species: names}, err
+	case "turns":
+		turns, err := number(1)
+		return TurnLimit{Turns: turns}, err
+	case "above", "below":
+		names, err := species(1)
+		if err != nil || len(names) != 1 {
+			return nil, fmt.Errorf("warunek %q: użycie %s:<gatunek>:<próg>", text, fields[0])
+		}
+		threshold, err := number(2)
+		if fields[0] == "above" {
+			return PopulationAbove{Species: names[0], Threshold: threshold}, err
+		}
+		return PopulationBelow{Species: names[0], Threshold: threshold}, err
+	case "steady":
+		window, err := number(1)
+		if err != nil {
+			return nil, err
+		}
+		if window < 2 {
+			return nil, fmt.Errorf("warunek %q: okno musi obejmować co najmniej 2 tury", text)
+		}
+		tolerance := 0.05
+		if argument(2) != "" {
+			if tolerance, err = strconv.ParseFloat(argument(2), 64); err != nil || !(tolerance >= 0) {
+				return nil, fmt.Errorf("warunek %q: niepoprawna tolerancja %q", text, argument(2))
+			}
+		}
+		return &SteadyState{Window: window, Tolerance: tolerance}, nil
+	case "time":
+		limit, err := time.ParseDuration(argument(1))
+		if err != nil {
+			return nil, fmt.Errorf("warunek %q: niepoprawny czas %q", text, argument(1))
+		}
+		return &WallClock{Limit: limit, started: time.Now()}, nil
+	}
+	return nil, fmt.Errorf("nieznany warunek %q", text)
+}